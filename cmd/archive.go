@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/cmd/common"
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+var archiveCmd = &cobra.Command{
+	SilenceUsage: true,
+	Example: "archive --dir ./site --checksum\n" +
+		"archive --dir ./site --profile prod --out site.zip",
+	Use:               "archive",
+	Short:             "Build the deployment archive of a local directory",
+	Long:              "Build the archive that deploy would send for a local directory, without logging in, to save it or print a checksum of its contents. The archive is the same every time for the same files.",
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: common.PrerunValidation,
+	RunE:              Archive,
+}
+
+func init() {
+	flags.AddFlags(archiveCmd, flags.Directory)
+	flags.AddFlags(archiveCmd, flags.ArchiveOut)
+	flags.AddFlags(archiveCmd, flags.Checksum)
+	flags.AddFlags(archiveCmd, flags.Profile)
+	flags.AddFlags(archiveCmd, flags.EnvFiles)
+	AppCmd = append(AppCmd, archiveCmd)
+}
+
+func Archive(cmd *cobra.Command, _ []string) (err error) {
+	fields := make(logrus.Fields)
+	fields["start"] = time.Now()
+	fields["process"] = "archive"
+	logging.WithFields(fields).Info(color.Green.Sprint("Starting Archive"))
+
+	var out string
+	if out, err = cmd.Flags().GetString(flags.ArchiveOut.Name); err != nil {
+		return
+	}
+	var checksum bool
+	if checksum, err = cmd.Flags().GetBool(flags.Checksum.Name); err != nil {
+		return
+	}
+	if out == "" && !checksum {
+		err = fmt.Errorf("archive needs --%v, --%v or both", flags.ArchiveOut.Name, flags.Checksum.Name)
+		return
+	}
+
+	var profile *pkg.Profile
+	if profile, err = common.Profile(cmd); err != nil {
+		return
+	}
+	var variables util.Variables
+	if variables, err = common.DeployVariables(cmd, profile); err != nil {
+		return
+	}
+	fields["variables"] = len(variables)
+
+	var targetDirectory string
+	if targetDirectory, err = cmd.Flags().GetString(flags.Directory.Name); err != nil {
+		return
+	} else if targetDirectory == "" {
+		targetDirectory = "."
+	}
+	fields["targetDirectory"] = targetDirectory
+
+	var payload []byte
+	if payload, err = pkg.ArchiveWithVariables(targetDirectory, nil, variables); err != nil {
+		return
+	}
+	fields["archiveBytes"] = len(payload)
+
+	if out != "" {
+		if err = os.WriteFile(out, payload, 0644); err != nil {
+			return
+		}
+		logging.WithFields(fields).Infof("Wrote archive %v", color.Cyan.Sprint(out))
+	}
+
+	if checksum {
+		var sum string
+		if sum, err = pkg.ArchiveChecksum(payload); err != nil {
+			return
+		}
+		fields["checksum"] = sum
+		fmt.Fprintln(cmd.OutOrStdout(), sum)
+	}
+
+	logging.WithFields(fields).Info(color.Green.Sprint("Finished Archive"))
+	return
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// ConfirmDeploy shows the summary and asks for the deploy to be confirmed,
+// unless --yes was given or there's no terminal to ask on. Protected deploys
+// have to be confirmed by typing the host name, and are refused when there's
+// no terminal to ask on.
+func ConfirmDeploy(cmd *cobra.Command, summary pkg.DeploySummary, protected bool) (err error) {
+	var yes bool
+	if yes, err = cmd.Flags().GetBool(flags.Yes.Name); err != nil {
+		return
+	} else if yes {
+		logging.Get().Debug("Skipping deploy confirmation")
+		return
+	}
+
+	if !util.IsTerminal(cmd.InOrStdin()) {
+		if protected {
+			err = fmt.Errorf("refusing to deploy to protected host %v without a terminal to confirm on; pass --%v to deploy anyway", summary.Host, flags.Yes.Name)
+		}
+		return
+	}
+
+	// prompt on stderr, so structured output on stdout isn't interrupted
+	out := cmd.ErrOrStderr()
+	if err = summary.WriteTable(out); err != nil {
+		return
+	}
+	fmt.Fprintln(out)
+
+	var confirmed bool
+	if confirmed, err = pkg.ConfirmDeploy(cmd.InOrStdin(), out, summary.Host, protected); err != nil {
+		return
+	} else if !confirmed {
+		err = fmt.Errorf("deploy to %v was not confirmed", summary.Host)
+	}
+	return
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/pkg/flags"
+)
+
+// OptionalLogin stops cobra from requiring the login flags, for commands
+// that only need to log in some of the time. Those commands call Login
+// when they do.
+func OptionalLogin(cmd *cobra.Command) {
+	for _, flag := range flags.NLXLoginFlags {
+		_ = cmd.Flags().SetAnnotation(flag.Name, cobra.BashCompOneRequiredFlag, []string{"false"})
+	}
+}
+
+// Login returns the login flag values, failing the same way cobra does
+// if any of them are missing
+func Login(cmd *cobra.Command) (host, username, password string, err error) {
+	if host, err = cmd.Flags().GetString(flags.PlinyHost.Name); err != nil {
+		return
+	}
+	if username, err = cmd.Flags().GetString(flags.Username.Name); err != nil {
+		return
+	}
+	if password, err = cmd.Flags().GetString(flags.Password.Name); err != nil {
+		return
+	}
+
+	var missing []string
+	for _, flag := range []struct {
+		name  string
+		value string
+	}{
+		{flags.PlinyHost.Name, host},
+		{flags.Password.Name, password},
+		{flags.Username.Name, username},
+	} {
+		if flag.value == "" {
+			missing = append(missing, fmt.Sprintf(`"%v"`, flag.name))
+		}
+	}
+	if len(missing) > 0 {
+		err = fmt.Errorf("required flag(s) %v not set", strings.Join(missing, ", "))
+	}
+
+	return
+}
@@ -2,14 +2,25 @@ package common
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
+	"github.com/skuid/skuid-cli/pkg"
 	"github.com/skuid/skuid-cli/pkg/constants"
 	"github.com/skuid/skuid-cli/pkg/flags"
 	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
 )
 
-// PrerunValidation sets up logging according to command flags
+// PrerunValidation sets up logging according to command flags, and applies
+// the config file's settings for everything that writes retrieved metadata
 func PrerunValidation(cmd *cobra.Command, _ []string) error {
+	// json and yaml output has stdout to itself, so that it can be parsed
+	if output := cmd.Flags().Lookup(flags.Output.Name); output != nil {
+		if format, err := pkg.ValidateOutputFormat(output.Value.String()); err == nil && format != pkg.OUTPUT_FORMAT_TABLE {
+			logging.SetStderr()
+		}
+	}
+
 	logging.Get().Infof("Skuid CLI Version %v", constants.VERSION_NAME)
 
 	// set verbosity
@@ -44,5 +55,14 @@ func PrerunValidation(cmd *cobra.Command, _ []string) error {
 	if fileLoggingEnabled {
 		logging.SetFileLogging(loggingDirectory)
 	}
+
+	// retrieve, diff, promote and snapshots all strip the same fields and
+	// write keys in the same order
+	if viper.IsSet(constants.CONFIG_VOLATILE_FIELDS) {
+		util.SetVolatileFields(viper.GetStringMapStringSlice(constants.CONFIG_VOLATILE_FIELDS))
+	}
+	if viper.IsSet(constants.CONFIG_KEY_ORDER) {
+		util.SetKeyOrder(viper.GetStringMapStringSlice(constants.CONFIG_KEY_ORDER))
+	}
 	return nil
 }
@@ -0,0 +1,126 @@
+package common_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/skuid/skuid-cli/cmd/common"
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/constants"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestPrerunValidationStructuredOutput(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		output      string
+		unmarshal   func([]byte, any) error
+	}{
+		{
+			description: "json",
+			output:      pkg.OUTPUT_FORMAT_JSON,
+			unmarshal:   json.Unmarshal,
+		},
+		{
+			description: "yaml",
+			output:      pkg.OUTPUT_FORMAT_YAML,
+			unmarshal:   yaml.Unmarshal,
+		},
+		{
+			description: "table",
+			output:      pkg.OUTPUT_FORMAT_TABLE,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			dir := t.TempDir()
+			stdout, err := os.Create(filepath.Join(dir, "stdout"))
+			assert.NoError(t, err)
+			stderr, err := os.Create(filepath.Join(dir, "stderr"))
+			assert.NoError(t, err)
+
+			originalStdout, originalStderr := os.Stdout, os.Stderr
+			os.Stdout, os.Stderr = stdout, stderr
+			logging.Reset()
+			defer func() {
+				os.Stdout, os.Stderr = originalStdout, originalStderr
+				logging.Reset()
+			}()
+
+			root := &cobra.Command{Use: "skuid"}
+			flags.AddFlags(root, flags.Verbose, flags.Trace, flags.FileLogging, flags.Diagnostic)
+			flags.AddFlags(root, flags.FileLoggingDirectory)
+			cmd := &cobra.Command{
+				Use:               "retrieve",
+				PersistentPreRunE: common.PrerunValidation,
+				RunE: func(cmd *cobra.Command, _ []string) error {
+					logging.Get().Info("Printing Retrieve Plan")
+					if tc.unmarshal == nil {
+						return nil
+					}
+					return pkg.WriteStructured(os.Stdout, tc.output, pkg.NlxMetadata{Pages: []string{"Home"}})
+				},
+			}
+			flags.AddFlags(cmd, flags.Output)
+			root.AddCommand(cmd)
+			root.SetArgs([]string{"retrieve", "--" + flags.Output.Name, tc.output})
+			assert.NoError(t, root.Execute())
+
+			written, err := os.ReadFile(stdout.Name())
+			assert.NoError(t, err)
+			logged, err := os.ReadFile(stderr.Name())
+			assert.NoError(t, err)
+
+			// table output is for people, and logs go along with it
+			if tc.unmarshal == nil {
+				assert.Contains(t, string(written), "Printing Retrieve Plan")
+				return
+			}
+
+			var metadata pkg.NlxMetadata
+			assert.NoError(t, tc.unmarshal(written, &metadata))
+			assert.Equal(t, []string{"Home"}, metadata.Pages)
+			assert.Contains(t, string(logged), "Skuid CLI Version")
+			assert.Contains(t, string(logged), "Printing Retrieve Plan")
+		})
+	}
+}
+
+func TestPrerunValidationConfig(t *testing.T) {
+	viper.Set(constants.CONFIG_VOLATILE_FIELDS, map[string]interface{}{"pages": []string{"meta.etag"}})
+	viper.Set(constants.CONFIG_KEY_ORDER, map[string]interface{}{"datasources": []string{"type", "name"}})
+	defer func() {
+		viper.Reset()
+		util.VolatileFields = util.CopyVolatileFields(util.DefaultVolatileFields)
+		util.KeyOrder = util.DefaultKeyOrderPolicy
+		logging.Reset()
+	}()
+
+	// every command that writes retrieved metadata strips the configured
+	// fields and orders keys as configured, not only retrieve
+	root := &cobra.Command{Use: "skuid"}
+	flags.AddFlags(root, flags.Verbose, flags.Trace, flags.FileLogging, flags.Diagnostic)
+	flags.AddFlags(root, flags.FileLoggingDirectory)
+	root.AddCommand(&cobra.Command{
+		Use:               "diff",
+		PersistentPreRunE: common.PrerunValidation,
+		RunE:              func(*cobra.Command, []string) error { return nil },
+	})
+	root.SetArgs([]string{"diff"})
+	root.SetOut(io.Discard)
+	assert.NoError(t, root.Execute())
+
+	assert.Equal(t, []string{"meta.etag"}, util.VolatileFields["pages"])
+	assert.Equal(t, util.DefaultVolatileFields["apps"], util.VolatileFields["apps"])
+	assert.Equal(t, []string{"type", "name"}, util.KeyOrder.LeadingFor("datasources"))
+	assert.Equal(t, []string{"name"}, util.KeyOrder.LeadingFor("pages"))
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// Profiles reads the profiles named with --profile
+func Profiles(cmd *cobra.Command) (profiles []pkg.Profile, err error) {
+	var names []string
+	if names, err = cmd.Flags().GetStringArray(flags.Profile.Name); err != nil {
+		return
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		var profile pkg.Profile
+		if profile, err = pkg.GetProfile(name); err != nil {
+			return
+		}
+		profiles = append(profiles, profile)
+	}
+	return
+}
+
+// Profile reads the profile named with --profile, for commands that only
+// take one. The profile is nil if none was named.
+func Profile(cmd *cobra.Command) (profile *pkg.Profile, err error) {
+	var profiles []pkg.Profile
+	if profiles, err = Profiles(cmd); err != nil {
+		return
+	}
+	switch len(profiles) {
+	case 0:
+	case 1:
+		profile = &profiles[0]
+	default:
+		err = fmt.Errorf("%v only takes one --%v", cmd.CommandPath(), flags.Profile.Name)
+	}
+	return
+}
+
+// DeployVariables returns the values for placeholders in deployed metadata:
+// the profile's variables, if there's a profile, overridden by the env files
+// in order
+func DeployVariables(cmd *cobra.Command, profile *pkg.Profile) (variables util.Variables, err error) {
+	variables = util.Variables{}
+
+	if profile != nil {
+		if variables, err = profile.GetVariables(); err != nil {
+			return
+		}
+	}
+
+	var envFiles []string
+	if envFiles, err = cmd.Flags().GetStringArray(flags.EnvFiles.Name); err != nil {
+		return
+	}
+	for _, envFile := range envFiles {
+		var fileVariables util.Variables
+		if fileVariables, err = util.ReadVariablesFile(envFile); err != nil {
+			return
+		}
+		variables = variables.Merge(fileVariables)
+	}
+
+	return
+}
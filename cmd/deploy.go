@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gookit/color"
@@ -11,6 +13,7 @@ import (
 	"github.com/skuid/skuid-cli/pkg"
 	"github.com/skuid/skuid-cli/pkg/flags"
 	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
 )
 
 var deployCmd = &cobra.Command{
@@ -23,11 +26,29 @@ var deployCmd = &cobra.Command{
 }
 
 func init() {
-	flags.AddFlags(deployCmd, flags.NLXLoginFlags...)
+	flags.AddFlags(deployCmd, flags.Hosts)
+	flags.AddFlags(deployCmd, flags.Username, flags.Password)
 	flags.AddFlags(deployCmd, flags.Directory, flags.AppName)
 	flags.AddFlags(deployCmd, flags.IgnoreSkuidDb)
 	flags.AddFlags(deployCmd, flags.IgnoreCompatibilityCheck)
-	flags.AddFlags(deployCmd, flags.Pages)
+	flags.AddFlags(deployCmd, flags.SkipValidation)
+	flags.AddFlags(deployCmd, flags.Pages, flags.Modules, flags.Types)
+	flags.AddFlags(deployCmd, flags.NoModule)
+	flags.AddFlags(deployCmd, flags.DryRun, flags.FailOnWarnings)
+	flags.AddFlags(deployCmd, flags.Snapshot)
+	flags.AddFlags(deployCmd, flags.Resume)
+	flags.AddFlags(deployCmd, flags.Yes)
+	flags.AddFlags(deployCmd, flags.MaxPayloadSize)
+	flags.AddFlags(deployCmd, flags.Parallelism)
+	flags.AddFlags(deployCmd, flags.FailFast)
+	flags.AddFlags(deployCmd, flags.Report)
+	flags.AddFlags(deployCmd, flags.ChangedSince)
+	flags.AddFlags(deployCmd, flags.OnlyChanged, flags.Force)
+	flags.AddFlags(deployCmd, flags.Prune)
+	flags.AddFlags(deployCmd, flags.ShowIgnored)
+	flags.AddFlags(deployCmd, flags.Profile)
+	flags.AddFlags(deployCmd, flags.EnvFiles)
+	flags.AddFlags(deployCmd, flags.Output)
 	AppCmd = append(AppCmd, deployCmd)
 }
 
@@ -37,11 +58,47 @@ func Deploy(cmd *cobra.Command, _ []string) (err error) {
 	fields["process"] = "deploy"
 	logging.WithFields(fields).Info(color.Green.Sprint("Starting Deploy"))
 
-	// get required authentication arguments
-	host, err := cmd.Flags().GetString(flags.PlinyHost.Name)
-	if err != nil {
+	var dryRun, snapshot bool
+	if dryRun, err = cmd.Flags().GetBool(flags.DryRun.Name); err != nil {
+		return
+	}
+	if snapshot, err = cmd.Flags().GetBool(flags.Snapshot.Name); err != nil {
+		return
+	}
+	var resume, prune bool
+	if resume, err = cmd.Flags().GetBool(flags.Resume.Name); err != nil {
+		return
+	}
+	if prune, err = cmd.Flags().GetBool(flags.Prune.Name); err != nil {
 		return
 	}
+	if resume && (dryRun || snapshot || prune) {
+		err = fmt.Errorf("--%v can't be used with --%v, --%v or --%v", flags.Resume.Name, flags.DryRun.Name, flags.Snapshot.Name, flags.Prune.Name)
+		return
+	}
+	// the services don't plan deletes, so pruning only lists what's missing
+	if prune && !dryRun {
+		err = fmt.Errorf("--%v only lists what would be deleted, so it has to be used with --%v", flags.Prune.Name, flags.DryRun.Name)
+		return
+	}
+	fields["resume"] = resume
+	fields["prune"] = prune
+
+	var reportFiles []string
+	if reportFiles, err = cmd.Flags().GetStringArray(flags.Report.Name); err != nil {
+		return
+	}
+
+	// validate the output format before we do any work
+	var outputFormat string
+	if outputFormat, err = cmd.Flags().GetString(flags.Output.Name); err != nil {
+		return
+	} else if outputFormat, err = pkg.ValidateOutputFormat(outputFormat); err != nil {
+		return
+	}
+	fields["dryRun"] = dryRun
+
+	// get required authentication arguments
 	username, err := cmd.Flags().GetString(flags.Username.Name)
 	if err != nil {
 		return
@@ -51,6 +108,35 @@ func Deploy(cmd *cobra.Command, _ []string) (err error) {
 		return
 	}
 
+	// find the sites to deploy to, from the hosts and profiles
+	var hosts []string
+	if hosts, err = cmd.Flags().GetStringArray(flags.Hosts.Name); err != nil {
+		return
+	}
+	var profiles []pkg.Profile
+	if profiles, err = common.Profiles(cmd); err != nil {
+		return
+	}
+	var targets []pkg.DeployTarget
+	if targets, err = pkg.DeployTargets(hosts, profiles); err != nil {
+		return
+	}
+	if len(targets) > 1 {
+		if dryRun || snapshot || resume || prune {
+			err = fmt.Errorf("--%v, --%v, --%v and --%v can only be used when deploying to one site", flags.DryRun.Name, flags.Snapshot.Name, flags.Resume.Name, flags.Prune.Name)
+			return
+		}
+		return deploySites(cmd, fields, targets, username, password, outputFormat, reportFiles)
+	}
+	target := targets[0]
+	host := target.Host
+
+	var variables util.Variables
+	if variables, err = common.DeployVariables(cmd, target.Profile); err != nil {
+		return
+	}
+	fields["variables"] = len(variables)
+
 	fields["host"] = host
 	fields["username"] = username
 	logging.WithFields(fields).Debug("Gathered credentials")
@@ -64,6 +150,131 @@ func Deploy(cmd *cobra.Command, _ []string) (err error) {
 	fields["authorized"] = true
 	logging.WithFields(fields).Info("Authentication Successful")
 
+	// get directory argument
+	var targetDirectory string
+	if targetDirectory, err = cmd.Flags().GetString(flags.Directory.Name); err != nil {
+		return
+	} else if targetDirectory == "" {
+		targetDirectory = "."
+	}
+
+	fields["targetDirectory"] = targetDirectory
+
+	if err = showIgnored(cmd, targetDirectory); err != nil {
+		return
+	}
+
+	var plans pkg.NlxDynamicPlanMap
+	var journal *pkg.DeployJournal
+	var deploymentPlan []byte
+	var hashes map[string]string
+	var warnings []string
+	var deployWarnings []pkg.DeployWarning
+	var deploySnapshot pkg.Snapshot
+	var pruneMetadata *pkg.NlxMetadata
+	if resume {
+		// the plan and the stages already applied come from the journal
+		if journal, err = resumeDeploy(targetDirectory, auth); err != nil {
+			return
+		}
+		plans = journal.Plans
+		fields["plans"] = len(plans)
+		// the journal refuses files that changed since the deploy started
+		if hashes, err = pkg.EntityHashes(targetDirectory, variables); err != nil {
+			return
+		}
+		if deployWarnings, err = evaluateWarnings(cmd, plans, nil); err != nil {
+			return reportDeploy(cmd, auth.Host, plans, deployWarnings, nil, err, outputFormat, reportFiles)
+		}
+		if err = common.ConfirmDeploy(cmd, pkg.NewDeploySummary(auth.Host, username, plans, nil), target.Protected()); err != nil {
+			return
+		}
+	} else {
+		if plans, deploymentPlan, hashes, warnings, err = planDeploy(cmd, fields, auth, targetDirectory, variables); err != nil {
+			return
+		}
+		if prune {
+			if pruneMetadata, err = planPrune(cmd, fields, auth, targetDirectory); err != nil {
+				return
+			}
+		}
+		if plans == nil && (pruneMetadata == nil || pruneMetadata.Count() == 0) {
+			return
+		}
+
+		if dryRun {
+			logging.WithFields(fields).Info("Dry Run: Printing Deployment Report")
+			return writePlanReport(cmd, auth, plans, targetDirectory, deploymentPlan, variables, warnings, pruneMetadata, outputFormat)
+		}
+
+		if deployWarnings, err = evaluateWarnings(cmd, plans, warnings); err != nil {
+			return reportDeploy(cmd, auth.Host, plans, deployWarnings, nil, err, outputFormat, reportFiles)
+		}
+
+		if err = common.ConfirmDeploy(cmd, pkg.NewDeploySummary(auth.Host, username, plans, warnings), target.Protected()); err != nil {
+			return
+		}
+
+		// take the snapshot before anything is deployed, and don't deploy
+		// without it
+		if snapshot {
+			logging.WithFields(fields).Info("Snapshotting Remote Metadata")
+			if deploySnapshot, err = pkg.CreateSnapshot(auth, plans, targetDirectory); err != nil {
+				logging.Get().Errorf("Unable to snapshot remote metadata: %v", err)
+				return
+			}
+			fields["snapshot"] = deploySnapshot.Name
+			logging.WithFields(fields).Infof("Snapshot %v saved", color.Cyan.Sprint(deploySnapshot.Name))
+		}
+
+		if journal, err = startDeployJournal(cmd, targetDirectory, auth.Host, plans, variables); err != nil {
+			return
+		}
+	}
+
+	logging.WithFields(fields).Info("Executing Deployment Plan")
+
+	var results []pkg.NlxDeploymentResult
+	_, results, err = pkg.ExecuteDeployJournal(auth, journal, targetDirectory, variables)
+
+	if snapshot {
+		status := pkg.SNAPSHOT_STATUS_DEPLOYED
+		if err != nil {
+			status = pkg.SNAPSHOT_STATUS_FAILED
+		}
+		if saveErr := deploySnapshot.Finish(status, err); saveErr != nil {
+			logging.Get().Warnf("Unable to record deploy in snapshot %v: %v", deploySnapshot.Name, saveErr)
+		}
+	}
+
+	fields["results"] = len(results)
+
+	for _, result := range results {
+		logging.Get().Tracef("result: %v", result.Url)
+	}
+
+	// report on whatever was deployed, even if the deploy failed part way
+	err = reportDeploy(cmd, auth.Host, plans, deployWarnings, results, err, outputFormat, reportFiles)
+
+	if err != nil {
+		if !journal.Complete() {
+			logging.Get().Infof("Run deploy again with --%v to continue from %v", flags.Resume.Name, pkg.DeployJournalPath(targetDirectory))
+		}
+		// Error will be logged via main.go
+		return
+	}
+
+	refreshDeployManifest(targetDirectory, auth.Host, plans, hashes)
+
+	logging.Get().Info(color.Green.Sprint("Finished Deploy"))
+
+	return
+}
+
+// planDeploy archives the directory, with the deploy command's filters
+// applied, and gets its deployment plan. No plans and no error means there's
+// nothing to deploy.
+func planDeploy(cmd *cobra.Command, fields logrus.Fields, auth *pkg.Authorization, targetDirectory string, variables util.Variables) (plans pkg.NlxDynamicPlanMap, deploymentPlan []byte, hashes map[string]string, warnings []string, err error) {
 	// only create the filter struct if it hasn't been created yet
 	var filter *pkg.NlxPlanFilter = nil
 	initFilter := func() {
@@ -113,19 +324,133 @@ func Deploy(cmd *cobra.Command, _ []string) (err error) {
 		filter.IgnoreCompatibilityCheck = ignoreCompatibilityCheck
 	}
 
-	// get directory argument
-	var targetDirectory string
-	if targetDirectory, err = cmd.Flags().GetString(flags.Directory.Name); err != nil {
+	// filter by module, which we read from the local page metadata
+	var modules []string
+	if modules, err = cmd.Flags().GetStringArray(flags.Modules.Name); err != nil {
 		return
-	} else if targetDirectory == "" {
-		targetDirectory = "."
+	}
+	var noModule bool
+	if noModule, err = cmd.Flags().GetBool(flags.NoModule.Name); err != nil {
+		return
+	}
+	if len(modules) > 0 || noModule {
+		var modulePages []string
+		if modulePages, err = pkg.GetModulePageNames(targetDirectory, modules, noModule); err != nil {
+			return
+		}
+		initFilter()
+		filter.PageNames = pkg.FilterPageNamesByModule(filter.PageNames, modulePages)
+		fields["modules"] = modules
+		fields["noModule"] = noModule
+		fields["pages"] = filter.PageNames
+		if len(filter.PageNames) == 0 {
+			err = fmt.Errorf("no pages found matching the module filter in %v", targetDirectory)
+			return
+		}
+	}
+
+	// only deploy what changed in git
+	var changedSince string
+	if changedSince, err = cmd.Flags().GetString(flags.ChangedSince.Name); err != nil {
+		return
+	}
+	var archiveFilter *pkg.NlxMetadata
+	if changedSince != "" {
+		fields["changedSince"] = changedSince
+		logging.WithFields(fields).Info("Finding Changed Metadata")
+
+		var changes pkg.ChangedEntities
+		if changes, err = pkg.GetChangedEntities(targetDirectory, changedSince); err != nil {
+			return
+		}
+		warnings = changes.Warnings()
+		for _, warning := range warnings {
+			logging.Get().Warnf("Warning %v", warning)
+		}
+
+		fields["changed"] = changes.Changed.Count()
+		if changes.Changed.Count() == 0 {
+			logging.WithFields(fields).Info(color.Green.Sprintf("Nothing has changed since %v, nothing to deploy", changedSince))
+			return
+		}
+		archiveFilter = &changes.Changed
+	}
+
+	// only deploy the metadata types asked for
+	var types []string
+	if types, err = cmd.Flags().GetStringArray(flags.Types.Name); err != nil {
+		return
+	}
+	if len(types) > 0 {
+		if err = pkg.ValidateMetadataTypes(types); err != nil {
+			return
+		}
+		fields["types"] = types
+
+		var local pkg.NlxMetadata
+		if local, err = pkg.LocalMetadata(targetDirectory); err != nil {
+			return
+		}
+		typed := local.OnlyTypes(types)
+		if archiveFilter != nil {
+			typed = archiveFilter.Intersect(typed)
+		}
+		if typed.Count() == 0 {
+			logging.WithFields(fields).Info(color.Green.Sprintf("Nothing of type %v to deploy", strings.Join(types, ", ")))
+			return
+		}
+		archiveFilter = &typed
+	}
+
+	// hash the entities before they're archived, so that the deploy manifest
+	// records what was deployed rather than what's there once it's done
+	if hashes, err = pkg.EntityHashes(targetDirectory, variables); err != nil {
+		return
+	}
+
+	// only deploy what changed since it was last deployed to the site
+	var onlyChanged, force bool
+	if onlyChanged, err = cmd.Flags().GetBool(flags.OnlyChanged.Name); err != nil {
+		return
+	}
+	if force, err = cmd.Flags().GetBool(flags.Force.Name); err != nil {
+		return
+	}
+	if onlyChanged && !force {
+		fields["onlyChanged"] = onlyChanged
+		logging.WithFields(fields).Info("Comparing Metadata with the Deploy Manifest")
+
+		var manifest *pkg.DeployManifest
+		if manifest, err = pkg.LoadDeployManifest(targetDirectory, auth.Host); err != nil {
+			return
+		}
+
+		changed := manifest.Changed(hashes)
+		if archiveFilter != nil {
+			changed = archiveFilter.Intersect(changed)
+		}
+		fields["changed"] = changed.Count()
+		if changed.Count() == 0 {
+			logging.WithFields(fields).Info(color.Green.Sprintf("Nothing has changed since the last deploy to %v, nothing to deploy", auth.Host))
+			return
+		}
+		archiveFilter = &changed
+	}
+
+	// catch malformed files here, rather than as an error from the deploy
+	var skipValidation bool
+	if skipValidation, err = cmd.Flags().GetBool(flags.SkipValidation.Name); err != nil {
+		return
+	}
+	if !skipValidation {
+		if err = validateDeploy(fields, auth, targetDirectory, archiveFilter, variables); err != nil {
+			return
+		}
 	}
 
-	fields["targetDirectory"] = targetDirectory
 	logging.WithFields(fields).Info("Getting Deployment Payload")
 
-	var deploymentPlan []byte
-	if deploymentPlan, err = pkg.Archive(targetDirectory, nil); err != nil {
+	if deploymentPlan, err = pkg.ArchiveWithVariables(targetDirectory, archiveFilter, variables); err != nil {
 		return
 	}
 
@@ -134,7 +459,6 @@ func Deploy(cmd *cobra.Command, _ []string) (err error) {
 
 	// get the plan
 	logging.WithFields(fields).Info("Getting Deployment Plan")
-	var plans pkg.NlxDynamicPlanMap
 	if _, plans, err = pkg.GetDeployPlan(auth, deploymentPlan, filter); err != nil {
 		logging.Get().Errorf("Unable to prepare deployment: %v", err)
 		return
@@ -142,22 +466,249 @@ func Deploy(cmd *cobra.Command, _ []string) (err error) {
 	logging.WithFields(fields).Info("Got Deployment Plan")
 
 	fields["plans"] = len(plans)
+	return
+}
 
-	logging.WithFields(fields).Info("Executing Deployment Plan")
+// validateDeploy checks the files that will be deployed, logging each problem
+// with its file and line
+func validateDeploy(fields logrus.Fields, auth *pkg.Authorization, targetDirectory string, filter *pkg.NlxMetadata, variables util.Variables) (err error) {
+	logging.WithFields(fields).Info("Validating Metadata")
 
-	var results []pkg.NlxDeploymentResult
-	if _, results, err = pkg.ExecuteDeployPlan(auth, plans, targetDirectory); err != nil {
-		// Error will be logged via main.go
+	// references to entities that aren't local have to be on the site
+	remote := func() (metadata pkg.NlxMetadata, err error) {
+		var plans pkg.NlxPlanPayload
+		if _, plans, err = pkg.GetRetrievePlan(auth, nil); err != nil {
+			return
+		}
+		return plans.Metadata(), nil
+	}
+
+	var problems []pkg.ValidationProblem
+	if problems, err = pkg.ValidateDirectory(targetDirectory, filter, variables, remote); err != nil {
+		return
+	}
+	fields["problems"] = len(problems)
+	if len(problems) == 0 {
 		return
 	}
 
-	fields["results"] = len(results)
+	for _, problem := range problems {
+		logging.Get().Errorf("%v", problem)
+	}
+	return fmt.Errorf("found %v problem(s) in %v; fix them, or use --%v to deploy anyway", len(problems), targetDirectory, flags.SkipValidation.Name)
+}
 
-	for _, result := range results {
-		logging.Get().Tracef("result: %v", result.Url)
+// writePlanReport prints what the plans would deploy, and what pruning would
+// delete, failing on the warnings that the warning policy doesn't allow
+func writePlanReport(cmd *cobra.Command, auth *pkg.Authorization, plans pkg.NlxDynamicPlanMap, targetDirectory string, deploymentPlan []byte, variables util.Variables, warnings []string, prune *pkg.NlxMetadata, outputFormat string) (err error) {
+	var report pkg.DeployPlanReport
+	if report, err = pkg.NewDeployPlanReport(auth, plans, targetDirectory, deploymentPlan, variables); err != nil {
+		return
+	}
+	report.Warnings = append(report.Warnings, warnings...)
+	report.Prune = prune
+	if outputFormat == pkg.OUTPUT_FORMAT_TABLE {
+		err = report.WriteTable(cmd.OutOrStdout())
+	} else {
+		err = pkg.WriteStructured(cmd.OutOrStdout(), outputFormat, report)
+	}
+	if err != nil {
+		return
 	}
+	_, err = evaluateWarnings(cmd, plans, warnings)
+	return
+}
 
-	logging.Get().Info(color.Green.Sprint("Finished Deploy"))
+// evaluateWarnings applies the warning policy, from --fail-on-warnings and
+// the config file, to the plans' warnings and any others, before anything is
+// deployed
+func evaluateWarnings(cmd *cobra.Command, plans pkg.NlxDynamicPlanMap, warnings []string) (evaluated []pkg.DeployWarning, err error) {
+	var failOnWarnings bool
+	if failOnWarnings, err = cmd.Flags().GetBool(flags.FailOnWarnings.Name); err != nil {
+		return
+	}
+	var policy pkg.WarningPolicy
+	if policy, err = pkg.GetWarningPolicy(failOnWarnings); err != nil {
+		return
+	}
+
+	// the plans' warnings are logged as they're deployed, so only those that
+	// stop the deploy are logged here
+	evaluated, err = policy.Evaluate(pkg.PlanWarnings(plans, warnings))
+	for _, warning := range evaluated {
+		if warning.Policy == pkg.WARNING_DENIED {
+			logging.Get().Warnf("Warning (%v) %v", warning.Policy, warning.Message)
+		}
+	}
+	return
+}
+
+// startDeployJournal replaces the directory's deploy journal, so that each
+// stage of the deploy is journaled and a failed deploy can be resumed.
+// Services whose payload is too large are deployed in batches.
+func startDeployJournal(cmd *cobra.Command, targetDirectory, host string, plans pkg.NlxDynamicPlanMap, variables util.Variables) (journal *pkg.DeployJournal, err error) {
+	var batches map[string][]pkg.DeployBatch
+	if batches, err = planBatches(cmd, plans, targetDirectory, variables); err != nil {
+		return
+	}
+
+	if previous, loadErr := pkg.LoadDeployJournal(targetDirectory); loadErr == nil && !previous.Complete() {
+		logging.Get().Warnf("Replacing the journal of an incomplete deploy (%v)", previous.Summary())
+	}
+	journal = pkg.NewDeployJournal(targetDirectory, host, plans)
+	journal.Batch(batches)
+	err = journal.Save()
+	return
+}
 
+// planBatches splits the plans whose payload is larger than
+// --max-payload-size into batches
+func planBatches(cmd *cobra.Command, plans pkg.NlxDynamicPlanMap, targetDirectory string, variables util.Variables) (batches map[string][]pkg.DeployBatch, err error) {
+	var maxPayloadSize int
+	if maxPayloadSize, err = payloadSizeLimit(cmd); err != nil {
+		return
+	}
+	if batches, err = pkg.PlanBatches(plans, targetDirectory, variables, maxPayloadSize); err != nil {
+		return
+	}
+	for _, name := range pkg.SortedPlanNames(plans) {
+		if planBatches := batches[name]; len(planBatches) > 0 {
+			logging.Get().Infof("Deploying %v in %v batches", color.Magenta.Sprint(plans[name].Type), len(planBatches))
+		}
+	}
+	return
+}
+
+// payloadSizeLimit reads --max-payload-size, in bytes
+func payloadSizeLimit(cmd *cobra.Command) (size int, err error) {
+	var megabytes int
+	if megabytes, err = cmd.Flags().GetInt(flags.MaxPayloadSize.Name); err != nil {
+		return
+	}
+	if megabytes < 0 {
+		err = fmt.Errorf("--%v can't be negative", flags.MaxPayloadSize.Name)
+		return
+	}
+	size = megabytes * 1024 * 1024
+	return
+}
+
+// reportDeploy prints the results of a deploy, with its evaluated warnings,
+// and writes them to each of the report files. The deploy's error takes
+// precedence over any report error.
+func reportDeploy(cmd *cobra.Command, host string, plans pkg.NlxDynamicPlanMap, warnings []pkg.DeployWarning, results []pkg.NlxDeploymentResult, deployErr error, outputFormat string, reportFiles []string) (err error) {
+	err = deployErr
+	report := pkg.NewDeployResultsReport(host, plans, results, err)
+	report.Warnings = warnings
+	for _, service := range report.Services {
+		if service.Status == pkg.DEPLOY_STATUS_DEPLOYED && service.Error != "" {
+			logging.Get().Warn(service.Error)
+		}
+	}
+	if outputFormat == pkg.OUTPUT_FORMAT_TABLE {
+		_ = report.WriteTable(cmd.OutOrStdout())
+	} else {
+		_ = pkg.WriteStructured(cmd.OutOrStdout(), outputFormat, report)
+	}
+	for _, reportFile := range reportFiles {
+		if reportErr := report.WriteFile(reportFile); reportErr != nil {
+			logging.Get().Errorf("Unable to write report %v: %v", reportFile, reportErr)
+			if err == nil {
+				err = reportErr
+			}
+		} else {
+			logging.Get().Infof("Wrote report %v", color.Cyan.Sprint(reportFile))
+		}
+	}
+	return
+}
+
+// planPrune finds what --prune lists: the entities on the site, within the
+// app, page, module and type filters, that the directory doesn't have
+func planPrune(cmd *cobra.Command, fields logrus.Fields, auth *pkg.Authorization, targetDirectory string) (prune *pkg.NlxMetadata, err error) {
+	var filter, deployFilter *pkg.NlxPlanFilter
+	if filter, deployFilter, err = siteFilters(cmd, fields); err != nil {
+		return
+	}
+
+	// the pages that may be pruned are resolved here the same way the
+	// deploy resolves them, with modules read from the directory
+	pageNames := deployFilter.PageNames
+	if filter != nil && (len(filter.Modules) > 0 || filter.NoModule) {
+		var modulePages []string
+		if modulePages, err = pkg.GetModulePageNames(targetDirectory, filter.Modules, filter.NoModule); err != nil {
+			return
+		}
+		pageNames = pkg.FilterPageNamesByModule(pageNames, modulePages)
+		if pageNames == nil {
+			pageNames = []string{}
+		}
+	}
+
+	var types []string
+	if types, err = cmd.Flags().GetStringArray(flags.Types.Name); err != nil {
+		return
+	}
+
+	logging.WithFields(fields).Info("Finding Metadata to Prune")
+
+	var metadata pkg.NlxMetadata
+	if metadata, err = pkg.GetPrunePlan(auth, filter, pageNames, targetDirectory, types); err != nil {
+		return
+	}
+	fields["pruned"] = metadata.Count()
+	if metadata.Count() == 0 {
+		logging.WithFields(fields).Info("Nothing to prune")
+	} else {
+		logging.WithFields(fields).Infof("%v entities on %v are missing locally", metadata.Count(), color.Cyan.Sprint(auth.Host))
+	}
+	prune = &metadata
+	return
+}
+
+// refreshDeployManifest records what a successful deploy deployed to the
+// host, with the hashes taken before it was archived. A deploy that succeeded
+// isn't failed because its manifest couldn't be saved; the next
+// --only-changed deploy just deploys more than it needs to.
+func refreshDeployManifest(targetDirectory, host string, plans pkg.NlxDynamicPlanMap, hashes map[string]string) {
+	if err := pkg.UpdateDeployManifest(targetDirectory, host, plans, hashes); err != nil {
+		logging.Get().Warnf("Unable to update the deploy manifest %v: %v", pkg.DeployManifestPath(targetDirectory, host), err)
+	}
+}
+
+// resumeDeploy loads the journal of the incomplete deploy of the directory
+func resumeDeploy(directory string, auth *pkg.Authorization) (journal *pkg.DeployJournal, err error) {
+	if journal, err = pkg.LoadDeployJournal(directory); err != nil {
+		return
+	}
+	if journal.Complete() {
+		err = fmt.Errorf("the last deploy of %v finished, there is nothing to resume", directory)
+		return
+	}
+	if journal.Host != auth.Host {
+		err = fmt.Errorf("the last deploy of %v was to %v, not %v", directory, journal.Host, auth.Host)
+		return
+	}
+	logging.Get().Infof("Resuming deploy started %v: %v", journal.Started.Local().Format(time.RFC1123), journal.Summary())
+	return
+}
+
+// showIgnored lists the files that .skuidignore excludes from the directory,
+// if asked to
+func showIgnored(cmd *cobra.Command, directory string) (err error) {
+	var show bool
+	if show, err = cmd.Flags().GetBool(flags.ShowIgnored.Name); err != nil || !show {
+		return
+	}
+
+	var ignored []string
+	if ignored, err = util.IgnoredFiles(directory); err != nil {
+		return
+	}
+
+	logging.Get().Infof("%v file(s) ignored by %v", len(ignored), util.SKUIDIGNORE_FILE)
+	for _, path := range ignored {
+		fmt.Fprintln(cmd.OutOrStdout(), path)
+	}
 	return
 }
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/cmd/common"
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+var deployPlanCmd = &cobra.Command{
+	SilenceUsage:      true,
+	Example:           "deploy plan -u myUser -p myPassword --host my-site.skuidsite.com --dir ./site --out plan.skuidplan",
+	Use:               "plan",
+	Short:             "Save a deployment plan to apply later",
+	Long:              "Build the deployment and request its plan, then save the plan, the target host and hashes of the files it deploys, so that exactly what was planned can be approved and applied with deploy apply",
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: common.PrerunValidation,
+	RunE:              DeployPlan,
+}
+
+var deployApplyCmd = &cobra.Command{
+	SilenceUsage:      true,
+	Example:           "deploy apply -u myUser -p myPassword --host my-site.skuidsite.com --dir ./site plan.skuidplan",
+	Use:               "apply <plan file>",
+	Short:             "Deploy a plan saved by deploy plan",
+	Long:              "Deploy a plan saved by deploy plan, refusing to if the target host or any of the files it deploys have changed since it was planned",
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: common.PrerunValidation,
+	RunE:              DeployApply,
+}
+
+func init() {
+	flags.AddFlags(deployPlanCmd, flags.NLXLoginFlags...)
+	flags.AddFlags(deployPlanCmd, flags.Directory, flags.AppName)
+	flags.AddFlags(deployPlanCmd, flags.IgnoreSkuidDb)
+	flags.AddFlags(deployPlanCmd, flags.IgnoreCompatibilityCheck)
+	flags.AddFlags(deployPlanCmd, flags.SkipValidation)
+	flags.AddFlags(deployPlanCmd, flags.Pages, flags.Modules, flags.Types)
+	flags.AddFlags(deployPlanCmd, flags.NoModule)
+	flags.AddFlags(deployPlanCmd, flags.FailOnWarnings)
+	flags.AddFlags(deployPlanCmd, flags.ChangedSince)
+	flags.AddFlags(deployPlanCmd, flags.OnlyChanged, flags.Force)
+	flags.AddFlags(deployPlanCmd, flags.Profile)
+	flags.AddFlags(deployPlanCmd, flags.EnvFiles)
+	flags.AddFlags(deployPlanCmd, flags.PlanOut)
+	flags.AddFlags(deployPlanCmd, flags.Output)
+
+	flags.AddFlags(deployApplyCmd, flags.NLXLoginFlags...)
+	flags.AddFlags(deployApplyCmd, flags.Directory)
+	flags.AddFlags(deployApplyCmd, flags.Report)
+	flags.AddFlags(deployApplyCmd, flags.FailOnWarnings)
+	flags.AddFlags(deployApplyCmd, flags.Yes)
+	flags.AddFlags(deployApplyCmd, flags.MaxPayloadSize)
+	flags.AddFlags(deployApplyCmd, flags.Profile)
+	flags.AddFlags(deployApplyCmd, flags.EnvFiles)
+	flags.AddFlags(deployApplyCmd, flags.Output)
+
+	deployCmd.AddCommand(deployPlanCmd, deployApplyCmd)
+}
+
+func DeployPlan(cmd *cobra.Command, _ []string) (err error) {
+	fields := make(logrus.Fields)
+	fields["start"] = time.Now()
+	fields["process"] = "deploy plan"
+	logging.WithFields(fields).Info(color.Green.Sprint("Starting Deploy Plan"))
+
+	var planFile string
+	if planFile, err = cmd.Flags().GetString(flags.PlanOut.Name); err != nil {
+		return
+	}
+	fields["planFile"] = planFile
+
+	var profile *pkg.Profile
+	if profile, err = common.Profile(cmd); err != nil {
+		return
+	}
+	var variables util.Variables
+	if variables, err = common.DeployVariables(cmd, profile); err != nil {
+		return
+	}
+	fields["variables"] = len(variables)
+
+	// validate the output format before we do any work
+	var outputFormat string
+	if outputFormat, err = cmd.Flags().GetString(flags.Output.Name); err != nil {
+		return
+	} else if outputFormat, err = pkg.ValidateOutputFormat(outputFormat); err != nil {
+		return
+	}
+
+	var targetDirectory string
+	if targetDirectory, err = cmd.Flags().GetString(flags.Directory.Name); err != nil {
+		return
+	} else if targetDirectory == "" {
+		targetDirectory = "."
+	}
+	fields["targetDirectory"] = targetDirectory
+
+	// get required authentication arguments
+	host, username, password, err := common.Login(cmd)
+	if err != nil {
+		return
+	}
+
+	fields["host"] = host
+	fields["username"] = username
+	logging.WithFields(fields).Debug("Gathered credentials")
+
+	var auth *pkg.Authorization
+	if auth, err = pkg.Authorize(host, username, password); err != nil {
+		return
+	}
+
+	fields["authorized"] = true
+	logging.WithFields(fields).Info("Authentication Successful")
+
+	var plans pkg.NlxDynamicPlanMap
+	var deploymentPlan []byte
+	var warnings []string
+	if plans, deploymentPlan, _, warnings, err = planDeploy(cmd, fields, auth, targetDirectory, variables); err != nil || plans == nil {
+		return
+	}
+
+	// save the plan even if it has warnings, so that they can be reviewed
+	var saved pkg.SavedDeployPlan
+	if saved, err = pkg.NewSavedDeployPlan(auth.Host, plans, targetDirectory, variables); err != nil {
+		return
+	}
+	if err = saved.Write(planFile); err != nil {
+		return
+	}
+	logging.WithFields(fields).Infof("Saved Deployment Plan to %v", color.Cyan.Sprint(planFile))
+
+	return writePlanReport(cmd, auth, plans, targetDirectory, deploymentPlan, variables, warnings, nil, outputFormat)
+}
+
+func DeployApply(cmd *cobra.Command, args []string) (err error) {
+	fields := make(logrus.Fields)
+	fields["start"] = time.Now()
+	fields["process"] = "deploy apply"
+	logging.WithFields(fields).Info(color.Green.Sprint("Starting Deploy Apply"))
+
+	planFile := args[0]
+	fields["planFile"] = planFile
+
+	var reportFiles []string
+	if reportFiles, err = cmd.Flags().GetStringArray(flags.Report.Name); err != nil {
+		return
+	}
+
+	var profile *pkg.Profile
+	if profile, err = common.Profile(cmd); err != nil {
+		return
+	}
+	var variables util.Variables
+	if variables, err = common.DeployVariables(cmd, profile); err != nil {
+		return
+	}
+	fields["variables"] = len(variables)
+
+	var outputFormat string
+	if outputFormat, err = cmd.Flags().GetString(flags.Output.Name); err != nil {
+		return
+	} else if outputFormat, err = pkg.ValidateOutputFormat(outputFormat); err != nil {
+		return
+	}
+
+	var targetDirectory string
+	if targetDirectory, err = cmd.Flags().GetString(flags.Directory.Name); err != nil {
+		return
+	} else if targetDirectory == "" {
+		targetDirectory = "."
+	}
+	fields["targetDirectory"] = targetDirectory
+
+	// get required authentication arguments
+	host, username, password, err := common.Login(cmd)
+	if err != nil {
+		return
+	}
+
+	fields["host"] = host
+	fields["username"] = username
+
+	// refuse to deploy anything other than what was planned, before logging in
+	var saved pkg.SavedDeployPlan
+	if saved, err = pkg.ReadSavedDeployPlan(planFile); err != nil {
+		return
+	}
+	fields["planned"] = saved.Created
+	var hashes map[string]string
+	if hashes, err = pkg.EntityHashes(targetDirectory, variables); err != nil {
+		return
+	}
+	if err = saved.Verify(host, targetDirectory, variables); err != nil {
+		return
+	}
+	logging.WithFields(fields).Info("Verified Deployment Plan")
+
+	var auth *pkg.Authorization
+	if auth, err = pkg.Authorize(host, username, password); err != nil {
+		return
+	}
+	// keep the host the plan was made with, so the journal matches it
+	auth.Host = saved.Host
+
+	fields["authorized"] = true
+	logging.WithFields(fields).Info("Authentication Successful")
+
+	var warnings []pkg.DeployWarning
+	if warnings, err = evaluateWarnings(cmd, saved.Plans, nil); err != nil {
+		return reportDeploy(cmd, auth.Host, saved.Plans, warnings, nil, err, outputFormat, reportFiles)
+	}
+
+	if err = common.ConfirmDeploy(cmd, pkg.NewDeploySummary(auth.Host, username, saved.Plans, nil), pkg.DeployTarget{Host: auth.Host, Profile: profile}.Protected()); err != nil {
+		return
+	}
+
+	var journal *pkg.DeployJournal
+	if journal, err = startDeployJournal(cmd, targetDirectory, auth.Host, saved.Plans, variables); err != nil {
+		return
+	}
+	// deploy the files that were verified, not whatever is there by the time
+	// each stage runs
+	journal.Expect(saved.Hashes)
+	if err = journal.Save(); err != nil {
+		return
+	}
+
+	logging.WithFields(fields).Info("Executing Deployment Plan")
+
+	var results []pkg.NlxDeploymentResult
+	_, results, err = pkg.ExecuteDeployJournal(auth, journal, targetDirectory, variables)
+	fields["results"] = len(results)
+
+	if err = reportDeploy(cmd, auth.Host, saved.Plans, warnings, results, err, outputFormat, reportFiles); err != nil {
+		if !journal.Complete() {
+			logging.Get().Infof("Run deploy again with --%v to continue from %v", flags.Resume.Name, pkg.DeployJournalPath(targetDirectory))
+		}
+		// Error will be logged via main.go
+		return
+	}
+
+	refreshDeployManifest(targetDirectory, auth.Host, saved.Plans, hashes)
+
+	logging.WithFields(fields).Info(color.Green.Sprint("Finished Deploy Apply"))
+
+	return
+}
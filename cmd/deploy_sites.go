@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"sync"
+
+	"github.com/gookit/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/cmd/common"
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// deploySites runs the same deploy against each of the targets, several at
+// a time, then reports how each site went. Every site is planned and
+// deployed on its own, with its own login, so one site failing doesn't
+// stop the others unless --fail-fast is set.
+func deploySites(cmd *cobra.Command, fields logrus.Fields, targets []pkg.DeployTarget, username, password, outputFormat string, reportFiles []string) (err error) {
+	var parallelism int
+	if parallelism, err = cmd.Flags().GetInt(flags.Parallelism.Name); err != nil {
+		return
+	}
+	var failFast bool
+	if failFast, err = cmd.Flags().GetBool(flags.FailFast.Name); err != nil {
+		return
+	}
+
+	var maxPayloadSize int
+	if maxPayloadSize, err = payloadSizeLimit(cmd); err != nil {
+		return
+	}
+
+	var targetDirectory string
+	if targetDirectory, err = cmd.Flags().GetString(flags.Directory.Name); err != nil {
+		return
+	} else if targetDirectory == "" {
+		targetDirectory = "."
+	}
+
+	fields["sites"] = len(targets)
+	fields["parallelism"] = parallelism
+	fields["failFast"] = failFast
+	fields["targetDirectory"] = targetDirectory
+	fields["username"] = username
+	logging.WithFields(fields).Infof("Deploying to %v sites", len(targets))
+
+	if err = showIgnored(cmd, targetDirectory); err != nil {
+		return
+	}
+
+	// sites that are ready at the same time are confirmed one at a time
+	var confirmMutex sync.Mutex
+
+	report := pkg.DeploySites(targets, parallelism, failFast, func(target pkg.DeployTarget) (results *pkg.DeployResultsReport, err error) {
+		siteFields := make(logrus.Fields, len(fields)+2)
+		for key, value := range fields {
+			siteFields[key] = value
+		}
+		siteFields["host"] = target.Host
+		siteFields["profile"] = target.ProfileName()
+
+		site := color.Cyan.Sprintf("[%v]", target.Host)
+		defer func() {
+			if err != nil {
+				logging.Get().Errorf("%v Deploy failed: %v", site, err)
+			}
+		}()
+
+		var variables util.Variables
+		if variables, err = common.DeployVariables(cmd, target.Profile); err != nil {
+			return
+		}
+
+		logging.Get().Infof("%v Authenticating", site)
+		var auth *pkg.Authorization
+		if auth, err = pkg.Authorize(target.Host, username, password); err != nil {
+			return
+		}
+
+		logging.Get().Infof("%v Planning", site)
+		var plans pkg.NlxDynamicPlanMap
+		var hashes map[string]string
+		var warnings []string
+		if plans, _, hashes, warnings, err = planDeploy(cmd, siteFields, auth, targetDirectory, variables); err != nil {
+			return
+		} else if plans == nil {
+			// nothing to deploy is a successful deploy of nothing
+			siteReport := pkg.NewDeployResultsReport(auth.Host, plans, nil, nil)
+			return &siteReport, nil
+		}
+
+		var deployWarnings []pkg.DeployWarning
+		if deployWarnings, err = evaluateWarnings(cmd, plans, warnings); err != nil {
+			siteReport := pkg.NewDeployResultsReport(auth.Host, plans, nil, err)
+			siteReport.Warnings = deployWarnings
+			return &siteReport, err
+		}
+
+		confirmMutex.Lock()
+		err = common.ConfirmDeploy(cmd, pkg.NewDeploySummary(auth.Host, username, plans, warnings), target.Protected())
+		confirmMutex.Unlock()
+		if err != nil {
+			return
+		}
+
+		logging.Get().Infof("%v Deploying", site)
+		var deployResults []pkg.NlxDeploymentResult
+		_, deployResults, err = pkg.ExecuteBatchedDeployPlan(auth, plans, targetDirectory, variables, maxPayloadSize)
+
+		siteReport := pkg.NewDeployResultsReport(auth.Host, plans, deployResults, err)
+		siteReport.Warnings = deployWarnings
+		if err == nil {
+			refreshDeployManifest(targetDirectory, auth.Host, plans, hashes)
+			inserted, updated, deleted, _ := siteReport.Totals()
+			logging.Get().Infof("%v %v", site, color.Green.Sprintf("Deployed: %v inserted, %v updated, %v deleted", inserted, updated, deleted))
+		}
+		return &siteReport, err
+	})
+
+	if outputFormat == pkg.OUTPUT_FORMAT_TABLE {
+		_ = report.WriteTable(cmd.OutOrStdout())
+	} else {
+		_ = pkg.WriteStructured(cmd.OutOrStdout(), outputFormat, report)
+	}
+	for _, reportFile := range reportFiles {
+		if reportErr := report.WriteFile(reportFile); reportErr != nil {
+			logging.Get().Errorf("Unable to write report %v: %v", reportFile, reportErr)
+			if err == nil {
+				err = reportErr
+			}
+		} else {
+			logging.Get().Infof("Wrote report %v", color.Cyan.Sprint(reportFile))
+		}
+	}
+
+	if siteErr := report.Err(); siteErr != nil {
+		// Error will be logged via main.go
+		return siteErr
+	}
+	if err != nil {
+		return
+	}
+
+	logging.Get().Info(color.Green.Sprintf("Finished Deploy to %v sites", len(targets)))
+
+	return
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/cmd/common"
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/logging"
+)
+
+var diffCmd = &cobra.Command{
+	SilenceUsage: true,
+	Example: "diff -u myUser -p myPassword --host my-site.skuidsite.com --dir ./site\n" +
+		"diff ./release-1 ./release-2",
+	Use:   "diff [directoryA directoryB]",
+	Short: "Compare local Skuid metadata with a Skuid NLX Site, or two local directories",
+	Long: "Retrieve Skuid metadata from a Skuid NLX Site and compare it with the metadata in a local directory, showing what a deploy would change. " +
+		"Given two directories, compare them offline instead.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("diff takes either no directories or two directories, received %v", len(args))
+		}
+		return nil
+	},
+	PersistentPreRunE: common.PrerunValidation,
+	RunE:              Diff,
+}
+
+func init() {
+	flags.AddFlags(diffCmd, flags.NLXLoginFlags...)
+	flags.AddFlags(diffCmd, flags.Directory, flags.AppName)
+	flags.AddFlags(diffCmd, flags.Pages)
+	flags.AddFlags(diffCmd, flags.Output)
+	// login is only required when comparing with a site
+	common.OptionalLogin(diffCmd)
+	AppCmd = append(AppCmd, diffCmd)
+}
+
+func Diff(cmd *cobra.Command, args []string) (err error) {
+	fields := make(logrus.Fields)
+	fields["start"] = time.Now()
+	fields["process"] = "diff"
+	logging.WithFields(fields).Info(color.Green.Sprint("Starting Diff"))
+
+	// validate the output format before we do any work
+	var outputFormat string
+	if outputFormat, err = cmd.Flags().GetString(flags.Output.Name); err != nil {
+		return
+	} else if outputFormat, err = pkg.ValidateOutputFormat(outputFormat); err != nil {
+		return
+	}
+
+	writeResult := func(result pkg.DiffResult) error {
+		fields["added"] = result.Summary.Added
+		fields["removed"] = result.Summary.Removed
+		fields["modified"] = result.Summary.Modified
+		if outputFormat == pkg.OUTPUT_FORMAT_TABLE {
+			return result.WriteText(cmd.OutOrStdout())
+		}
+		return pkg.WriteStructured(cmd.OutOrStdout(), outputFormat, result)
+	}
+
+	// two directories are compared offline
+	if len(args) == 2 {
+		fields["from"] = args[0]
+		fields["to"] = args[1]
+		for _, directory := range args {
+			if info, e := os.Stat(directory); e != nil {
+				return e
+			} else if !info.IsDir() {
+				return fmt.Errorf("%v is not a directory", directory)
+			}
+		}
+
+		logging.WithFields(fields).Info("Comparing Directories")
+		var result pkg.DiffResult
+		if result, err = pkg.DiffDirectories(args[0], args[1], nil); err != nil {
+			return
+		}
+		if err = writeResult(result); err != nil {
+			return
+		}
+
+		logging.WithFields(fields).Info(color.Green.Sprint("Finished Diff"))
+		return
+	}
+
+	// get required authentication arguments
+	host, username, password, err := common.Login(cmd)
+	if err != nil {
+		return
+	}
+
+	fields["host"] = host
+	fields["username"] = username
+	logging.WithFields(fields).Debug("Gathered credentials")
+
+	var auth *pkg.Authorization
+	if auth, err = pkg.Authorize(host, username, password); err != nil {
+		return
+	}
+
+	fields["authorized"] = true
+	logging.WithFields(fields).Info("Authentication Successful")
+
+	var filter *pkg.NlxPlanFilter = nil
+	initFilter := func() {
+		if filter == nil {
+			filter = &pkg.NlxPlanFilter{}
+		}
+	}
+
+	// filter by app name
+	var appName string
+	if appName, err = cmd.Flags().GetString(flags.AppName.Name); err != nil {
+		return
+	} else if appName != "" {
+		initFilter()
+		fields["appName"] = appName
+		filter.AppName = appName
+	}
+
+	// filter by page name
+	var pageNames []string
+	if pageNames, err = cmd.Flags().GetStringArray(flags.Pages.Name); err != nil {
+		return
+	} else if len(pageNames) > 0 {
+		initFilter()
+		fields["pages"] = pageNames
+		filter.PageNames = pageNames
+	}
+
+	var targetDirectory string
+	if targetDirectory, err = cmd.Flags().GetString(flags.Directory.Name); err != nil {
+		return
+	} else if targetDirectory == "" {
+		targetDirectory = "."
+	}
+	fields["targetDirectory"] = targetDirectory
+
+	// retrieve into a temporary directory so the local files are never touched
+	var remoteDirectory string
+	if remoteDirectory, err = os.MkdirTemp("", "skuid-diff"); err != nil {
+		return
+	}
+	defer os.RemoveAll(remoteDirectory)
+
+	logging.WithFields(fields).Info("Retrieving Remote Metadata")
+
+	var plans pkg.NlxPlanPayload
+	if plans, err = pkg.RetrieveToDirectory(auth, filter, remoteDirectory); err != nil {
+		return
+	}
+
+	logging.WithFields(fields).Info("Comparing Metadata")
+
+	// when filtered, only compare the local entities that were retrieved
+	var keep func(string) bool
+	if filter != nil {
+		keep = plans.FilterItem
+	}
+
+	var result pkg.DiffResult
+	if result, err = pkg.DiffDirectories(remoteDirectory, targetDirectory, keep); err != nil {
+		return
+	}
+	result.From = host
+
+	if err = writeResult(result); err != nil {
+		return
+	}
+
+	logging.WithFields(fields).Info(color.Green.Sprint("Finished Diff"))
+
+	return
+}
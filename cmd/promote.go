@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/cmd/common"
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+var promoteCmd = &cobra.Command{
+	SilenceUsage: true,
+	Example: "promote -u myUser -p myPassword --from uat --to prod --app myApp --diff\n" +
+		"promote -u myUser -p myPassword --from uat.skuidsite.com --to prod.skuidsite.com --types pages,apps --dry-run",
+	Use:               "promote",
+	Short:             "Deploy Skuid metadata from one Skuid NLX Site to another",
+	Long:              "Retrieve Skuid metadata from a Skuid NLX Site into a temporary directory and deploy it to another Skuid NLX Site, without touching any local directory. The sites are profile names or host URLs.",
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: common.PrerunValidation,
+	RunE:              Promote,
+}
+
+func init() {
+	flags.AddFlags(promoteCmd, flags.PromoteFrom, flags.PromoteTo)
+	flags.AddFlags(promoteCmd, flags.Username, flags.Password)
+	flags.AddFlags(promoteCmd, flags.AppName)
+	flags.AddFlags(promoteCmd, flags.Pages, flags.Modules, flags.Types)
+	flags.AddFlags(promoteCmd, flags.NoModule)
+	flags.AddFlags(promoteCmd, flags.IgnoreSkuidDb)
+	flags.AddFlags(promoteCmd, flags.IgnoreCompatibilityCheck)
+	flags.AddFlags(promoteCmd, flags.DryRun, flags.FailOnWarnings)
+	flags.AddFlags(promoteCmd, flags.PreviewDiff)
+	flags.AddFlags(promoteCmd, flags.Yes)
+	flags.AddFlags(promoteCmd, flags.MaxPayloadSize)
+	flags.AddFlags(promoteCmd, flags.Report)
+	flags.AddFlags(promoteCmd, flags.EnvFiles)
+	flags.AddFlags(promoteCmd, flags.Output)
+	AppCmd = append(AppCmd, promoteCmd)
+}
+
+func Promote(cmd *cobra.Command, _ []string) (err error) {
+	fields := make(logrus.Fields)
+	fields["start"] = time.Now()
+	fields["process"] = "promote"
+	logging.WithFields(fields).Info(color.Green.Sprint("Starting Promote"))
+
+	var dryRun, previewDiff bool
+	if dryRun, err = cmd.Flags().GetBool(flags.DryRun.Name); err != nil {
+		return
+	}
+	if previewDiff, err = cmd.Flags().GetBool(flags.PreviewDiff.Name); err != nil {
+		return
+	}
+	fields["dryRun"] = dryRun
+	fields["diff"] = previewDiff
+
+	var reportFiles []string
+	if reportFiles, err = cmd.Flags().GetStringArray(flags.Report.Name); err != nil {
+		return
+	}
+
+	var maxPayloadSize int
+	if maxPayloadSize, err = payloadSizeLimit(cmd); err != nil {
+		return
+	}
+
+	// validate the output format before we do any work
+	var outputFormat string
+	if outputFormat, err = cmd.Flags().GetString(flags.Output.Name); err != nil {
+		return
+	} else if outputFormat, err = pkg.ValidateOutputFormat(outputFormat); err != nil {
+		return
+	}
+	if previewDiff && outputFormat != pkg.OUTPUT_FORMAT_TABLE {
+		err = fmt.Errorf("--%v can only be used with --%v %v", flags.PreviewDiff.Name, flags.Output.Name, pkg.OUTPUT_FORMAT_TABLE)
+		return
+	}
+
+	// only promote the metadata types asked for
+	var types []string
+	if types, err = cmd.Flags().GetStringArray(flags.Types.Name); err != nil {
+		return
+	}
+	var keepType func(string) bool
+	if keepType, err = pkg.MetadataTypeFilter(types); err != nil {
+		return
+	}
+	if len(types) > 0 {
+		fields["types"] = types
+	}
+
+	// find the sites, each a profile name or a host
+	var fromValue, toValue string
+	if fromValue, err = cmd.Flags().GetString(flags.PromoteFrom.Name); err != nil {
+		return
+	}
+	if toValue, err = cmd.Flags().GetString(flags.PromoteTo.Name); err != nil {
+		return
+	}
+	var from, to pkg.DeployTarget
+	if from, to, err = pkg.PromoteSites(fromValue, toValue); err != nil {
+		return
+	}
+	fields["from"] = from.Host
+	fields["to"] = to.Host
+
+	// the target's variables are substituted into what's deployed to it
+	var variables util.Variables
+	if variables, err = common.DeployVariables(cmd, to.Profile); err != nil {
+		return
+	}
+	fields["variables"] = len(variables)
+
+	// get required authentication arguments
+	username, err := cmd.Flags().GetString(flags.Username.Name)
+	if err != nil {
+		return
+	}
+	password, err := cmd.Flags().GetString(flags.Password.Name)
+	if err != nil {
+		return
+	}
+	fields["username"] = username
+	logging.WithFields(fields).Debug("Gathered credentials")
+
+	var fromAuth, toAuth *pkg.Authorization
+	if fromAuth, err = pkg.Authorize(from.Host, username, password); err != nil {
+		return
+	}
+	if toAuth, err = pkg.Authorize(to.Host, username, password); err != nil {
+		return
+	}
+
+	fields["authorized"] = true
+	logging.WithFields(fields).Info("Authentication Successful")
+
+	retrieveFilter, deployFilter, err := siteFilters(cmd, fields)
+	if err != nil {
+		return
+	}
+
+	// retrieve into a temporary directory so no local files are touched
+	var sourceDirectory string
+	if sourceDirectory, err = os.MkdirTemp("", "skuid-promote"); err != nil {
+		return
+	}
+	defer os.RemoveAll(sourceDirectory)
+
+	logging.WithFields(fields).Infof("Retrieving Metadata from %v", color.Cyan.Sprint(from.Host))
+
+	var sourcePlans pkg.NlxPlanPayload
+	if sourcePlans, err = pkg.RetrieveToDirectory(fromAuth, retrieveFilter, sourceDirectory); err != nil {
+		return
+	}
+
+	if previewDiff {
+		if err = previewPromote(cmd, fields, from.Host, toAuth, retrieveFilter, sourcePlans, sourceDirectory, keepType); err != nil {
+			return
+		}
+	}
+
+	logging.WithFields(fields).Info("Getting Deployment Payload")
+
+	var deploymentPlan []byte
+	if deploymentPlan, err = pkg.ArchiveWithFilterFunc(sourceDirectory, keepType, variables); err != nil {
+		return
+	}
+
+	fields["deploymentBytes"] = len(deploymentPlan)
+	logging.WithFields(fields).Info("Getting Deployment Plan")
+
+	var plans pkg.NlxDynamicPlanMap
+	if _, plans, err = pkg.GetDeployPlan(toAuth, deploymentPlan, deployFilter); err != nil {
+		logging.Get().Errorf("Unable to prepare deployment: %v", err)
+		return
+	}
+	fields["plans"] = len(plans)
+
+	if dryRun {
+		logging.WithFields(fields).Info("Dry Run: Printing Deployment Report")
+		return writePlanReport(cmd, toAuth, plans, sourceDirectory, deploymentPlan, variables, nil, nil, outputFormat)
+	}
+
+	var warnings []pkg.DeployWarning
+	if warnings, err = evaluateWarnings(cmd, plans, nil); err != nil {
+		return reportDeploy(cmd, toAuth.Host, plans, warnings, nil, err, outputFormat, reportFiles)
+	}
+
+	if err = common.ConfirmDeploy(cmd, pkg.NewDeploySummary(toAuth.Host, username, plans, nil), to.Protected()); err != nil {
+		return
+	}
+
+	logging.WithFields(fields).Infof("Deploying Metadata to %v", color.Cyan.Sprint(to.Host))
+
+	var results []pkg.NlxDeploymentResult
+	_, results, err = pkg.ExecuteBatchedDeployPlan(toAuth, plans, sourceDirectory, variables, maxPayloadSize)
+	fields["results"] = len(results)
+
+	if err = reportDeploy(cmd, toAuth.Host, plans, warnings, results, err, outputFormat, reportFiles); err != nil {
+		// Error will be logged via main.go
+		return
+	}
+
+	logging.WithFields(fields).Info(color.Green.Sprint("Finished Promote"))
+
+	return
+}
+
+// siteFilters reads the filters to retrieve a site with, and the filter to
+// plan a deploy to a site with
+func siteFilters(cmd *cobra.Command, fields logrus.Fields) (retrieveFilter, deployFilter *pkg.NlxPlanFilter, err error) {
+	retrieveFilter = &pkg.NlxPlanFilter{}
+	deployFilter = &pkg.NlxPlanFilter{}
+
+	var appName string
+	if appName, err = cmd.Flags().GetString(flags.AppName.Name); err != nil {
+		return
+	} else if appName != "" {
+		fields["appName"] = appName
+		retrieveFilter.AppName = appName
+		deployFilter.AppName = appName
+	}
+
+	var pageNames []string
+	if pageNames, err = cmd.Flags().GetStringArray(flags.Pages.Name); err != nil {
+		return
+	} else if len(pageNames) > 0 {
+		fields["pages"] = pageNames
+		retrieveFilter.PageNames = pageNames
+		deployFilter.PageNames = pageNames
+	}
+
+	// modules are resolved by the source site, so the deploy only sees the
+	// pages that were retrieved
+	var modules []string
+	if modules, err = cmd.Flags().GetStringArray(flags.Modules.Name); err != nil {
+		return
+	} else if len(modules) > 0 {
+		fields["modules"] = modules
+		retrieveFilter.Modules = modules
+	}
+
+	var noModule bool
+	if noModule, err = cmd.Flags().GetBool(flags.NoModule.Name); err != nil {
+		return
+	} else if noModule {
+		fields["noModule"] = noModule
+		retrieveFilter.NoModule = noModule
+	}
+
+	if deployFilter.IgnoreSkuidDb, err = cmd.Flags().GetBool(flags.IgnoreSkuidDb.Name); err != nil {
+		return
+	}
+	if deployFilter.IgnoreCompatibilityCheck, err = cmd.Flags().GetBool(flags.IgnoreCompatibilityCheck.Name); err != nil {
+		return
+	}
+	fields["ignoreSkuidDb"] = deployFilter.IgnoreSkuidDb
+	fields["ignoreCompatibilityCheck"] = deployFilter.IgnoreCompatibilityCheck
+
+	// an empty retrieval filter retrieves the whole site
+	if retrieveFilter.AppName == "" && len(retrieveFilter.PageNames) == 0 && len(retrieveFilter.Modules) == 0 && !retrieveFilter.NoModule {
+		retrieveFilter = nil
+	}
+	return
+}
+
+// previewPromote retrieves the same metadata from the target site and prints
+// how the source site's metadata differs from it
+func previewPromote(cmd *cobra.Command, fields logrus.Fields, fromHost string, toAuth *pkg.Authorization, filter *pkg.NlxPlanFilter, sourcePlans pkg.NlxPlanPayload, sourceDirectory string, keepType func(string) bool) (err error) {
+	var targetDirectory string
+	if targetDirectory, err = os.MkdirTemp("", "skuid-promote-target"); err != nil {
+		return
+	}
+	defer os.RemoveAll(targetDirectory)
+
+	logging.WithFields(fields).Infof("Retrieving Metadata from %v", color.Cyan.Sprint(toAuth.Host))
+
+	var targetPlans pkg.NlxPlanPayload
+	if targetPlans, err = pkg.RetrieveToDirectory(toAuth, filter, targetDirectory); err != nil {
+		return
+	}
+
+	// when filtered, only compare what either site's retrieval included
+	keep := keepType
+	if filter != nil {
+		keep = func(item string) bool {
+			return keepType(item) && (sourcePlans.FilterItem(item) || targetPlans.FilterItem(item))
+		}
+	}
+
+	logging.WithFields(fields).Info("Comparing Metadata")
+
+	var result pkg.DiffResult
+	if result, err = pkg.DiffDirectories(targetDirectory, sourceDirectory, keep); err != nil {
+		return
+	}
+	// name the sites rather than their temporary directories
+	result.From = toAuth.Host
+	result.To = fromHost
+
+	fields["added"] = result.Summary.Added
+	fields["removed"] = result.Summary.Removed
+	fields["modified"] = result.Summary.Modified
+	return result.WriteText(cmd.OutOrStdout())
+}
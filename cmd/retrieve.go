@@ -60,6 +60,20 @@ func Retrieve(cmd *cobra.Command, _ []string) (err error) {
 	fields["start"] = start
 
 	logging.Get().Info(color.Green.Sprint("Starting Retrieve"))
+
+	var planOnly bool
+	if planOnly, err = cmd.Flags().GetBool(flags.PlanOnly.Name); err != nil {
+		return
+	}
+
+	// validate the output format before we do any work
+	var outputFormat string
+	if outputFormat, err = cmd.Flags().GetString(flags.Output.Name); err != nil {
+		return
+	} else if outputFormat, err = pkg.ValidateOutputFormat(outputFormat); err != nil {
+		return
+	}
+	fields["planOnly"] = planOnly
 	// get required arguments
 	host, err := cmd.Flags().GetString(flags.PlinyHost.Name)
 	if err != nil {
@@ -121,6 +135,25 @@ func Retrieve(cmd *cobra.Command, _ []string) (err error) {
 		filter.PageNames = pageNames
 	}
 
+	// filter by module, which pliny resolves against the page metadata
+	var modules []string
+	if modules, err = cmd.Flags().GetStringArray(flags.Modules.Name); err != nil {
+		return
+	} else if len(modules) > 0 {
+		initFilter()
+		fields["modules"] = modules
+		filter.Modules = modules
+	}
+
+	var noModule bool
+	if noModule, err = cmd.Flags().GetBool(flags.NoModule.Name); err != nil {
+		return
+	} else if noModule {
+		initFilter()
+		fields["noModule"] = noModule
+		filter.NoModule = noModule
+	}
+
 	var sinceStr string
 	since := time.Now()
 	hasSince := false
@@ -237,6 +270,15 @@ func Retrieve(cmd *cobra.Command, _ []string) (err error) {
 		}
 	}
 
+	// only print the plan; don't download anything or touch the directory
+	if planOnly {
+		logging.WithFields(fields).Info("Printing Retrieve Plan")
+		if outputFormat == pkg.OUTPUT_FORMAT_TABLE {
+			return pkg.WritePlanTable(cmd.OutOrStdout(), plans)
+		}
+		return pkg.WriteStructured(cmd.OutOrStdout(), outputFormat, plans)
+	}
+
 	var results []pkg.NlxRetrievalResult
 	if _, results, err = pkg.ExecuteRetrieval(auth, plans); err != nil {
 		return
@@ -256,6 +298,15 @@ func Retrieve(cmd *cobra.Command, _ []string) (err error) {
 	fields["directory"] = directory
 	logging.WithFields(fields).Infof("Target Directory is %v", color.Cyan.Sprint(directory))
 
+	// volatile fields, as configured, are removed as the results are written
+	var keepVolatileFields bool
+	if keepVolatileFields, err = cmd.Flags().GetBool(flags.KeepVolatileFields.Name); err != nil {
+		return
+	} else if keepVolatileFields {
+		util.ClearVolatileFields()
+	}
+	fields["volatileFields"] = util.VolatileFields
+
 	// TODO: put this behind a boolean command flag to avoid this process
 	pkg.ClearDirectories(directory)
 
@@ -282,7 +333,9 @@ func Retrieve(cmd *cobra.Command, _ []string) (err error) {
 func init() {
 	flags.AddFlags(retrieveCmd, flags.NLXLoginFlags...)
 	flags.AddFlags(retrieveCmd, flags.Directory, flags.AppName)
-	flags.AddFlags(retrieveCmd, flags.Pages)
+	flags.AddFlags(retrieveCmd, flags.Pages, flags.Modules)
+	flags.AddFlags(retrieveCmd, flags.NoModule, flags.PlanOnly, flags.KeepVolatileFields)
+	flags.AddFlags(retrieveCmd, flags.Output)
 	flags.AddFlags(retrieveCmd, flags.Since)
 	AppCmd = append(AppCmd, retrieveCmd)
 }
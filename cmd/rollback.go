@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/cmd/common"
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/logging"
+)
+
+var rollbackCmd = &cobra.Command{
+	SilenceUsage: true,
+	Example: "rollback -u myUser -p myPassword --host my-site.skuidsite.com --dir ./site\n" +
+		"rollback --dir ./site --list",
+	Use:               "rollback [snapshot]",
+	Short:             "Roll a Skuid NLX Site back to a snapshot",
+	Long:              "Redeploy a snapshot taken by deploy --snapshot, restoring the site to the way it was before that deploy. Without a snapshot name, the latest deployed snapshot is used.",
+	Args:              cobra.MaximumNArgs(1),
+	PersistentPreRunE: common.PrerunValidation,
+	RunE:              Rollback,
+}
+
+func init() {
+	flags.AddFlags(rollbackCmd, flags.NLXLoginFlags...)
+	flags.AddFlags(rollbackCmd, flags.Directory)
+	flags.AddFlags(rollbackCmd, flags.ListSnapshots)
+	flags.AddFlags(rollbackCmd, flags.Output)
+	// listing snapshots doesn't need a site
+	common.OptionalLogin(rollbackCmd)
+	AppCmd = append(AppCmd, rollbackCmd)
+}
+
+func Rollback(cmd *cobra.Command, args []string) (err error) {
+	fields := make(logrus.Fields)
+	fields["start"] = time.Now()
+	fields["process"] = "rollback"
+	logging.WithFields(fields).Info(color.Green.Sprint("Starting Rollback"))
+
+	var targetDirectory string
+	if targetDirectory, err = cmd.Flags().GetString(flags.Directory.Name); err != nil {
+		return
+	} else if targetDirectory == "" {
+		targetDirectory = "."
+	}
+	fields["targetDirectory"] = targetDirectory
+
+	var list bool
+	if list, err = cmd.Flags().GetBool(flags.ListSnapshots.Name); err != nil {
+		return
+	} else if list {
+		var outputFormat string
+		if outputFormat, err = cmd.Flags().GetString(flags.Output.Name); err != nil {
+			return
+		} else if outputFormat, err = pkg.ValidateOutputFormat(outputFormat); err != nil {
+			return
+		}
+
+		var snapshots []pkg.Snapshot
+		if snapshots, err = pkg.ListSnapshots(targetDirectory); err != nil {
+			return
+		}
+		if outputFormat == pkg.OUTPUT_FORMAT_TABLE {
+			return pkg.WriteSnapshotTable(cmd.OutOrStdout(), snapshots)
+		}
+		return pkg.WriteStructured(cmd.OutOrStdout(), outputFormat, snapshots)
+	}
+
+	// find the snapshot before logging in, so a typo fails fast
+	var snapshot pkg.Snapshot
+	if len(args) > 0 {
+		snapshot, err = pkg.LoadSnapshot(targetDirectory, args[0])
+	} else {
+		snapshot, err = pkg.LatestSnapshot(targetDirectory)
+	}
+	if err != nil {
+		return
+	}
+	fields["snapshot"] = snapshot.Name
+
+	// get required authentication arguments
+	host, username, password, err := common.Login(cmd)
+	if err != nil {
+		return
+	}
+
+	fields["host"] = host
+	fields["username"] = username
+	logging.WithFields(fields).Debug("Gathered credentials")
+
+	var auth *pkg.Authorization
+	if auth, err = pkg.Authorize(host, username, password); err != nil {
+		return
+	}
+
+	fields["authorized"] = true
+	logging.WithFields(fields).Info("Authentication Successful")
+
+	logging.WithFields(fields).Infof("Rolling Back to %v", color.Cyan.Sprint(snapshot.Name))
+
+	var results []pkg.NlxDeploymentResult
+	if results, err = pkg.Rollback(auth, &snapshot); err != nil {
+		return
+	}
+
+	fields["results"] = len(results)
+
+	for _, result := range results {
+		logging.Get().Tracef("result: %v", result.Url)
+	}
+
+	logging.WithFields(fields).Info(color.Green.Sprint("Finished Rollback"))
+
+	return
+}
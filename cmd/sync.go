@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/skuid/skuid-cli/cmd/common"
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/flags"
+	"github.com/skuid/skuid-cli/pkg/logging"
+)
+
+var syncCmd = &cobra.Command{
+	SilenceUsage: true,
+	Use:          "sync",
+	Short:        "Run the sync steps of a deploy on their own",
+	Long:         "Run the steps that a deploy runs after deploying metadata, such as after a deploy that failed part way",
+}
+
+var syncPermissionSetsCmd = &cobra.Command{
+	SilenceUsage:      true,
+	Example:           "sync permissionsets -u myUser -p myPassword --host my-site.skuidsite.com",
+	Use:               "permissionsets",
+	Short:             "Send the site's permission sets to the Skuid Cloud Data Service",
+	Long:              "Retrieve the site's app permission sets, with their ids, from Skuid NLX and send them to the Skuid Cloud Data Service to create their data source permissions",
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: common.PrerunValidation,
+	RunE:              SyncPermissionSets,
+}
+
+var syncDataSourcesCmd = &cobra.Command{
+	SilenceUsage:      true,
+	Example:           "sync datasources -u myUser -p myPassword --host my-site.skuidsite.com",
+	Use:               "datasources",
+	Short:             "Sync the site's data sources with the Skuid Cloud Data Service",
+	Long:              "Ask Skuid NLX to sync its data sources' external ids with the data sources in the Skuid Cloud Data Service",
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: common.PrerunValidation,
+	RunE:              SyncDataSources,
+}
+
+func init() {
+	flags.AddFlags(syncPermissionSetsCmd, flags.NLXLoginFlags...)
+	flags.AddFlags(syncDataSourcesCmd, flags.NLXLoginFlags...)
+
+	syncCmd.AddCommand(syncPermissionSetsCmd, syncDataSourcesCmd)
+	AppCmd = append(AppCmd, syncCmd)
+}
+
+// syncLogin authorizes with the site given on the command line
+func syncLogin(cmd *cobra.Command, fields logrus.Fields) (auth *pkg.Authorization, err error) {
+	host, username, password, err := common.Login(cmd)
+	if err != nil {
+		return
+	}
+
+	fields["host"] = host
+	fields["username"] = username
+	logging.WithFields(fields).Debug("Gathered credentials")
+
+	if auth, err = pkg.Authorize(host, username, password); err != nil {
+		return
+	}
+
+	fields["authorized"] = true
+	logging.WithFields(fields).Info("Authentication Successful")
+	return
+}
+
+func SyncPermissionSets(cmd *cobra.Command, _ []string) (err error) {
+	fields := make(logrus.Fields)
+	fields["start"] = time.Now()
+	fields["process"] = "sync permissionsets"
+	logging.WithFields(fields).Info(color.Green.Sprint("Starting Permission Set Sync"))
+
+	var auth *pkg.Authorization
+	if auth, err = syncLogin(cmd, fields); err != nil {
+		return
+	}
+
+	logging.WithFields(fields).Info("Retrieving Permission Sets")
+
+	var permissionSets []pkg.PermissionSetResult
+	if permissionSets, err = pkg.SyncPermissionSets(auth); err != nil {
+		return
+	}
+	fields["permissionSets"] = len(permissionSets)
+
+	logging.WithFields(fields).Info(color.Green.Sprintf("Finished Permission Set Sync: %v permission set(s) sent", len(permissionSets)))
+	return
+}
+
+func SyncDataSources(cmd *cobra.Command, _ []string) (err error) {
+	fields := make(logrus.Fields)
+	fields["start"] = time.Now()
+	fields["process"] = "sync datasources"
+	logging.WithFields(fields).Info(color.Green.Sprint("Starting Data Source Sync"))
+
+	var auth *pkg.Authorization
+	if auth, err = syncLogin(cmd, fields); err != nil {
+		return
+	}
+
+	if err = pkg.SyncDataSources(auth); err != nil {
+		return
+	}
+
+	logging.WithFields(fields).Info(color.Green.Sprint("Finished Data Source Sync"))
+	return
+}
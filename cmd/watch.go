@@ -30,6 +30,9 @@ var watchCmd = &cobra.Command{
 func init() {
 	flags.AddFlags(watchCmd, flags.NLXLoginFlags...)
 	flags.AddFlags(watchCmd, flags.Directory)
+	flags.AddFlags(watchCmd, flags.ShowIgnored)
+	flags.AddFlags(watchCmd, flags.Profile)
+	flags.AddFlags(watchCmd, flags.EnvFiles)
 	AppCmd = append(AppCmd, watchCmd)
 }
 
@@ -89,6 +92,27 @@ func Watch(cmd *cobra.Command, _ []string) (err error) {
 		return
 	}
 
+	ignoreDirectory := targetDir
+	if ignoreDirectory == "" {
+		ignoreDirectory = "."
+	}
+	var ignore *util.IgnoreMatcher
+	if ignore, err = util.LoadIgnore(ignoreDirectory); err != nil {
+		return
+	}
+	if err = showIgnored(cmd, ignoreDirectory); err != nil {
+		return
+	}
+
+	var profile *pkg.Profile
+	if profile, err = common.Profile(cmd); err != nil {
+		return
+	}
+	var variables util.Variables
+	if variables, err = common.DeployVariables(cmd, profile); err != nil {
+		return
+	}
+
 	// Create our watcher
 	w := watcher.New()
 
@@ -104,6 +128,10 @@ func Watch(cmd *cobra.Command, _ []string) (err error) {
 			case event := <-w.Event:
 				logging.WithFields(fields).Debug("Event Detected")
 				cleanRelativeFilePath := util.FromWindowsPath(strings.Split(event.Path, targetDirFriendly)[1])
+				if ignore.Ignored(cleanRelativeFilePath, event.IsDir()) {
+					logging.WithFields(fields).Debugf("Ignoring change to %v", cleanRelativeFilePath)
+					continue
+				}
 				dirSplit := strings.Split(cleanRelativeFilePath, string(filepath.Separator))
 				metadataType, remainder := dirSplit[1], dirSplit[2]
 				var changedEntity string
@@ -116,7 +144,7 @@ func Watch(cmd *cobra.Command, _ []string) (err error) {
 				}
 				logging.WithFields(fields).Debug("Detected change to metadata type: " + changedEntity)
 				go func() {
-					if err := pkg.DeployModifiedFiles(auth, targetDir, changedEntity); err != nil {
+					if err := pkg.DeployModifiedFiles(auth, targetDir, changedEntity, variables); err != nil {
 						w.Error <- err
 					}
 				}()
@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// deployBatchGroups are the metadata types that can be deployed in the same
+// batch, in the order they have to be deployed, so that what apps and pages
+// depend on, including the components in component packs, is deployed before
+// them. Component packs are deployed one per batch, since they're the
+// largest.
+var deployBatchGroups = [][]string{
+	{"site", "themes", "designsystems", "files", "authproviders", "dataservices", "datasources", "connectionvariables", "variables", "sessionvariables", "tables", "workflows", "documents"},
+	{"apps", "permissionsets", "sitepermissionsets"},
+	{"componentpacks"},
+	{"pages"},
+}
+
+// DeployBatch is the part of a plan's metadata that one request deploys, when
+// the plan's payload is too large to deploy at once
+type DeployBatch struct {
+	Number   int         `json:"number"`
+	Of       int         `json:"of"`
+	Metadata NlxMetadata `json:"metadata"`
+}
+
+func (batch DeployBatch) String() string {
+	return fmt.Sprintf("batch %v of %v", batch.Number, batch.Of)
+}
+
+// BatchMetadata splits the metadata into batches of at most maxSize bytes,
+// given the size of each entity, in dependency order. A batch only has
+// metadata types from one group of deployBatchGroups, and an entity larger
+// than maxSize is a batch on its own.
+func BatchMetadata(metadata NlxMetadata, maxSize int, entitySize func(metadataType, name string) int) (batches []NlxMetadata) {
+	for _, group := range deployBatchGroups {
+		var batch NlxMetadata
+		size := 0
+		flush := func() {
+			if batch.Count() > 0 {
+				batches = append(batches, batch)
+			}
+			batch = NlxMetadata{}
+			size = 0
+		}
+
+		for _, metadataType := range group {
+			names, _ := metadata.GetFieldValueByName(metadataType)
+			for _, name := range names {
+				entity := entitySize(metadataType, name)
+				if batch.Count() > 0 && (metadataType == "componentpacks" || size+entity > maxSize) {
+					flush()
+				}
+				batch.Add(metadataType, name)
+				size += entity
+			}
+		}
+		flush()
+	}
+	return
+}
+
+// PlanBatches splits each plan whose payload from the directory is larger
+// than maxSize bytes into batches, by plan name. Plans that fit in one
+// request aren't included, and nothing is split if maxSize isn't positive.
+func PlanBatches(plans NlxDynamicPlanMap, targetDir string, variables util.Variables, maxSize int) (batches map[string][]DeployBatch, err error) {
+	batches = make(map[string][]DeployBatch)
+	if maxSize <= 0 {
+		return
+	}
+
+	for _, name := range SortedPlanNames(plans) {
+		plan := plans[name]
+
+		var payload []byte
+		if payload, err = ArchiveWithVariables(targetDir, &plan.Metadata, variables); err != nil {
+			return
+		}
+		if len(payload) <= maxSize {
+			continue
+		}
+
+		// estimate each entity's share of the compressed payload from the
+		// size of its files
+		var sizes map[string]int64
+		var total int64
+		if sizes, total, err = entityFileSizes(targetDir, plan.Metadata); err != nil {
+			return
+		}
+		ratio := 1.0
+		if total > 0 {
+			ratio = float64(len(payload)) / float64(total)
+		}
+		entitySize := func(metadataType, name string) int {
+			return int(float64(sizes[entityKey(metadataType, name)]) * ratio)
+		}
+
+		var planBatches []NlxMetadata
+		for _, batch := range BatchMetadata(plan.Metadata, maxSize, entitySize) {
+			// the estimate can be off, so split batches that are still too large
+			var split []NlxMetadata
+			if split, err = splitOversizedBatch(batch, targetDir, variables, maxSize); err != nil {
+				return
+			}
+			planBatches = append(planBatches, split...)
+		}
+
+		for i, batch := range planBatches {
+			batches[name] = append(batches[name], DeployBatch{Number: i + 1, Of: len(planBatches), Metadata: batch})
+		}
+	}
+	return
+}
+
+// entityFileSizes returns the total size of the files of each entity in the
+// metadata, by entityKey, and the size of all of them
+func entityFileSizes(targetDir string, metadata NlxMetadata) (sizes map[string]int64, total int64, err error) {
+	var files map[string]string
+	if files, err = metadataFiles(targetDir, metadata.FilterItem); err != nil {
+		return
+	}
+
+	sizes = make(map[string]int64)
+	for relativePath, filePath := range files {
+		var info os.FileInfo
+		if info, err = os.Stat(filePath); err != nil {
+			return
+		}
+		metadataType, name, _ := EntityFromPath(relativePath)
+		sizes[entityKey(metadataType, name)] += info.Size()
+		total += info.Size()
+	}
+	return
+}
+
+// splitOversizedBatch halves the batch until each part's payload fits, or is
+// a single entity
+func splitOversizedBatch(batch NlxMetadata, targetDir string, variables util.Variables, maxSize int) (batches []NlxMetadata, err error) {
+	var payload []byte
+	if payload, err = ArchiveWithVariables(targetDir, &batch, variables); err != nil {
+		return
+	}
+	if len(payload) <= maxSize || batch.Count() < 2 {
+		return []NlxMetadata{batch}, nil
+	}
+
+	var first, second NlxMetadata
+	half := batch.Count() / 2
+	added := 0
+	for _, metadataType := range GetMetadataTypeDirNames() {
+		names, _ := batch.GetFieldValueByName(metadataType)
+		for _, name := range names {
+			if added < half {
+				first.Add(metadataType, name)
+			} else {
+				second.Add(metadataType, name)
+			}
+			added++
+		}
+	}
+
+	for _, part := range []NlxMetadata{first, second} {
+		var split []NlxMetadata
+		if split, err = splitOversizedBatch(part, targetDir, variables, maxSize); err != nil {
+			return
+		}
+		batches = append(batches, split...)
+	}
+	return
+}
@@ -0,0 +1,131 @@
+package pkg_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestBatchMetadata(t *testing.T) {
+	metadata := pkg.NlxMetadata{
+		Pages:          []string{"Home", "Orders", "Accounts"},
+		Apps:           []string{"Sales"},
+		DataSources:    []string{"Db"},
+		ComponentPacks: []string{"charts", "maps"},
+		Themes:         []string{"Dark"},
+	}
+	sizes := map[string]int{"Home": 40, "Orders": 40, "Accounts": 150}
+	entitySize := func(metadataType, name string) int {
+		if size, ok := sizes[name]; ok {
+			return size
+		}
+		return 10
+	}
+
+	for _, tc := range []struct {
+		description string
+		maxSize     int
+		expected    []pkg.NlxMetadata
+	}{
+		{
+			description: "dependencies first and component packs alone",
+			maxSize:     100,
+			expected: []pkg.NlxMetadata{
+				{Themes: []string{"Dark"}, DataSources: []string{"Db"}},
+				{Apps: []string{"Sales"}},
+				{ComponentPacks: []string{"charts"}},
+				{ComponentPacks: []string{"maps"}},
+				{Pages: []string{"Home", "Orders"}},
+				// larger than the limit on its own
+				{Pages: []string{"Accounts"}},
+			},
+		},
+		{
+			description: "everything fits",
+			maxSize:     1000,
+			expected: []pkg.NlxMetadata{
+				{Themes: []string{"Dark"}, DataSources: []string{"Db"}},
+				{Apps: []string{"Sales"}},
+				{ComponentPacks: []string{"charts"}},
+				{ComponentPacks: []string{"maps"}},
+				{Pages: []string{"Home", "Orders", "Accounts"}},
+			},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, pkg.BatchMetadata(metadata, tc.maxSize, entitySize))
+		})
+	}
+}
+
+func TestBatchMetadataEveryType(t *testing.T) {
+	// every metadata type is in exactly one batch
+	var metadata pkg.NlxMetadata
+	for _, metadataType := range pkg.GetMetadataTypeDirNames() {
+		metadata.Add(metadataType, "entity")
+	}
+	var batched pkg.NlxMetadata
+	count := 0
+	for _, batch := range pkg.BatchMetadata(metadata, 1000, func(string, string) int { return 1 }) {
+		batched = batched.Union(batch)
+		count += batch.Count()
+	}
+	assert.Equal(t, metadata, batched)
+	assert.Equal(t, metadata.Count(), count)
+}
+
+func TestPlanBatches(t *testing.T) {
+	dir := t.TempDir()
+	var pages []string
+	for _, name := range []string{"a", "b", "c", "d"} {
+		// varied enough content that it doesn't compress away
+		var content strings.Builder
+		for i := 0; i < 2000; i++ {
+			content.WriteString(name)
+			content.WriteString(strings.Repeat("x", i%17))
+			content.WriteString(strings.Repeat("y", (i*7)%13))
+		}
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "pages"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "pages", name+".json"), []byte(`{"name":"`+name+`"}`), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "pages", name+".xml"), []byte(content.String()), 0644))
+		pages = append(pages, name)
+	}
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Metadata: pkg.NlxMetadata{Pages: pages}},
+	}
+
+	batches, err := pkg.PlanBatches(plans, dir, nil, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, batches)
+
+	payload, err := pkg.Archive(dir, nil)
+	assert.NoError(t, err)
+
+	batches, err = pkg.PlanBatches(plans, dir, nil, len(payload)+1)
+	assert.NoError(t, err)
+	assert.Empty(t, batches)
+
+	maxSize := len(payload) / 2
+	batches, err = pkg.PlanBatches(plans, dir, nil, maxSize)
+	assert.NoError(t, err)
+	planBatches := batches[pkg.METADATA_PLAN_KEY]
+	assert.Greater(t, len(planBatches), 1)
+
+	// every page is deployed once, in batches that fit
+	var deployed []string
+	for i, batch := range planBatches {
+		assert.Equal(t, i+1, batch.Number)
+		assert.Equal(t, len(planBatches), batch.Of)
+		deployed = append(deployed, batch.Metadata.Pages...)
+
+		batchPayload, err := pkg.Archive(dir, &batch.Metadata)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(batchPayload), maxSize)
+	}
+	assert.Equal(t, pages, deployed)
+}
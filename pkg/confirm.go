@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// DeploySummary is what a deploy is asked to be confirmed with
+type DeploySummary struct {
+	Host     string
+	Username string
+	// Counts are the number of entities of each type being deployed, in
+	// metadata directory order
+	Counts   []DeploySummaryCount
+	Warnings []string
+}
+
+type DeploySummaryCount struct {
+	Type  string
+	Count int
+}
+
+// NewDeploySummary summarizes the plans, and any warnings beyond the plans' own
+func NewDeploySummary(host, username string, plans NlxDynamicPlanMap, warnings []string) (summary DeploySummary) {
+	summary = DeploySummary{
+		Host:     host,
+		Username: username,
+	}
+
+	var metadata NlxMetadata
+	for _, name := range SortedPlanNames(plans) {
+		metadata = metadata.Union(plans[name].Metadata)
+		summary.Warnings = append(summary.Warnings, plans[name].Warnings...)
+	}
+	summary.Warnings = append(summary.Warnings, warnings...)
+
+	for _, metadataType := range GetMetadataTypeDirNames() {
+		if names, _ := metadata.GetFieldValueByName(metadataType); len(names) > 0 {
+			summary.Counts = append(summary.Counts, DeploySummaryCount{Type: metadataType, Count: len(names)})
+		}
+	}
+	return
+}
+
+// WriteTable writes a human readable version of the summary
+func (summary DeploySummary) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "host\t%v\n", summary.Host)
+	fmt.Fprintf(tw, "user\t%v\n\n", summary.Username)
+
+	total := 0
+	for _, count := range summary.Counts {
+		fmt.Fprintf(tw, "%v\t%v\n", count.Type, count.Count)
+		total += count.Count
+	}
+	fmt.Fprintf(tw, "total\t%v\n\n", total)
+
+	for _, warning := range summary.Warnings {
+		fmt.Fprintf(tw, "warning\t%v\n", warning)
+	}
+	fmt.Fprintf(tw, "warnings\t%v\n", len(summary.Warnings))
+
+	return tw.Flush()
+}
+
+// ConfirmDeploy prompts for confirmation of a deploy to the host. Deploys to
+// protected hosts are only confirmed by typing the host name; otherwise
+// "y" or "yes" confirms.
+func ConfirmDeploy(in io.Reader, out io.Writer, host string, protected bool) (confirmed bool, err error) {
+	if protected {
+		fmt.Fprintf(out, "%v is protected. Type the host name to deploy: ", host)
+	} else {
+		fmt.Fprintf(out, "Deploy to %v? [y/N]: ", host)
+	}
+
+	var answer string
+	if answer, err = bufio.NewReader(in).ReadString('\n'); err != nil && err != io.EOF {
+		return
+	}
+	err = nil
+	answer = strings.TrimSpace(answer)
+
+	if protected {
+		return answer != "" && sameHost(answer, host), nil
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
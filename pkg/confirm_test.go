@@ -0,0 +1,64 @@
+package pkg_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestNewDeploySummary(t *testing.T) {
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{
+			Metadata: pkg.NlxMetadata{Pages: []string{"Home", "Other"}, DataSources: []string{"Orders"}},
+			Warnings: []string{"page Other has no app"},
+		},
+		pkg.DATA_PLAN_KEY: pkg.NlxPlan{
+			Metadata: pkg.NlxMetadata{DataSources: []string{"Orders"}},
+		},
+	}
+
+	summary := pkg.NewDeploySummary("https://example.skuidsite.com", "admin", plans, []string{"pages Old was deleted locally"})
+	assert.Equal(t, []pkg.DeploySummaryCount{
+		{Type: "datasources", Count: 1},
+		{Type: "pages", Count: 2},
+	}, summary.Counts)
+	assert.Equal(t, []string{"page Other has no app", "pages Old was deleted locally"}, summary.Warnings)
+
+	var out bytes.Buffer
+	assert.NoError(t, summary.WriteTable(&out))
+	assert.Regexp(t, `host\s+https://example.skuidsite.com`, out.String())
+	assert.Regexp(t, `user\s+admin`, out.String())
+	assert.Regexp(t, `pages\s+2`, out.String())
+	assert.Regexp(t, `total\s+3`, out.String())
+	assert.Regexp(t, `warnings\s+2`, out.String())
+}
+
+func TestConfirmDeploy(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		answer      string
+		protected   bool
+		expected    bool
+	}{
+		{description: "yes", answer: "yes\n", expected: true},
+		{description: "y", answer: "Y\n", expected: true},
+		{description: "no", answer: "n\n", expected: false},
+		{description: "nothing", answer: "", expected: false},
+		{description: "protected host name", answer: "example.skuidsite.com\n", protected: true, expected: true},
+		{description: "protected full host", answer: "https://example.skuidsite.com\n", protected: true, expected: true},
+		{description: "protected yes", answer: "yes\n", protected: true, expected: false},
+		{description: "protected other host", answer: "other.skuidsite.com\n", protected: true, expected: false},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			var out bytes.Buffer
+			confirmed, err := pkg.ConfirmDeploy(strings.NewReader(tc.answer), &out, "https://example.skuidsite.com", tc.protected)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, confirmed)
+			assert.Contains(t, out.String(), "https://example.skuidsite.com")
+		})
+	}
+}
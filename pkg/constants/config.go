@@ -0,0 +1,10 @@
+package constants
+
+// keys read from the .skuid config file
+const (
+	CONFIG_VOLATILE_FIELDS = "volatileFields"
+	CONFIG_KEY_ORDER       = "keyOrder"
+	CONFIG_PROFILES        = "profiles"
+	CONFIG_WARNINGS_ALLOW  = "warnings.allow"
+	CONFIG_WARNINGS_DENY   = "warnings.deny"
+)
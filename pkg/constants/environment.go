@@ -21,6 +21,9 @@ const (
 	ENV_SKUID_IGNORE_SKUIDDB         = "SKUID_IGNORE_SKUIDDB"
 	ENV_SKUID_RETRIEVE_SINCE         = "SKUID_RETRIEVE_SINCE_DATE"
 	SKUID_IGNORE_COMPATIBILITY_CHECK = "SKUID_IGNORE_COMPATIBILITY_CHECK"
+	ENV_SKUID_SNAPSHOT               = "SKUID_SNAPSHOT"
+	ENV_SKUID_PROFILE                = "SKUID_PROFILE"
+	ENV_SKUID_MAX_PAYLOAD_SIZE       = "SKUID_MAX_PAYLOAD_SIZE"
 )
 
 const (
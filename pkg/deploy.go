@@ -6,8 +6,8 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/gookit/color"
 	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
 )
 
 var (
@@ -94,8 +94,8 @@ func GetDeployPlan(auth *Authorization, deploymentPlan []byte, filter *NlxPlanFi
 	return
 }
 
-func DeployModifiedFiles(auth *Authorization, targetDir, modifiedFile string) (err error) {
-	planBody, err := ArchivePartial(targetDir, modifiedFile)
+func DeployModifiedFiles(auth *Authorization, targetDir, modifiedFile string, variables util.Variables) (err error) {
+	planBody, err := ArchivePartial(targetDir, modifiedFile, variables)
 	if err != nil {
 		return
 	}
@@ -109,7 +109,7 @@ func DeployModifiedFiles(auth *Authorization, targetDir, modifiedFile string) (e
 
 	logging.Get().Tracef("Received Deployment Plan for (%v), Deploying", modifiedFile)
 
-	_, _, err = ExecuteDeployPlan(auth, plan, targetDir)
+	_, _, err = ExecuteDeployPlan(auth, plan, targetDir, variables)
 	if err != nil {
 		return
 	}
@@ -128,139 +128,23 @@ func DeployModifiedFiles(auth *Authorization, targetDir, modifiedFile string) (e
 // 4. After its deployed take the app permission set ids from the pliny deploy and deploy those permission sets to warden
 // 5. If metadata and data was deployed, send a request to pliny to sync its datasources' external_ids with warden datasource
 // ids, in case they changed during the deploy
-func ExecuteDeployPlan(auth *Authorization, plans NlxDynamicPlanMap, targetDir string) (duration time.Duration, planResults []NlxDeploymentResult, err error) {
-	start := time.Now()
-	defer func() { duration = time.Since(start) }()
-	logging.Get().Trace("Executing Deploy Plan")
-
-	metaPlan, mok := plans[METADATA_PLAN_KEY]
-	dataPlan, dok := plans[DATA_PLAN_KEY]
-	if !mok && !dok {
-		return
-	}
-
-	// Warning for metaplan
-	for _, warning := range metaPlan.Warnings {
-		logging.Get().Warnf("Warning %v", warning)
-	}
-
-	// Warning for dataplan
-	for _, warning := range dataPlan.Warnings {
-		logging.Get().Warnf("Warning %v", warning)
-	}
-
-	planResults = make([]NlxDeploymentResult, 0)
-
-	executePlan := func(plan NlxPlan) (err error) {
-		logging.Get().Infof("Deploying %v", color.Magenta.Sprint(plan.Type))
-
-		logging.Get().Tracef("Archiving %v", targetDir)
-		payload, err := Archive(targetDir, &plan.Metadata)
-		if err != nil {
-			logging.Get().Trace("Error creating deployment ZIP archive")
-			return
-		}
-
-		headers := GeneratePlanHeaders(auth, plan)
-		logging.Get().Tracef("Plan Headers: %v\n", headers)
-
-		url := GenerateRoute(auth, plan)
-		logging.Get().Tracef("Plan Request: %v\n", url)
-
-		var response []byte
-		if response, err = Request(
-			url,
-			http.MethodPost,
-			payload,
-			headers,
-		); err == nil {
-			planResults = append(planResults, NlxDeploymentResult{
-				Plan: plan,
-				Url:  url,
-				Data: response,
-			})
-		} else {
-			logging.Get().Tracef("Url: %v", url)
-			logging.Get().Tracef("Error on request: %v\n", err.Error())
-			return
-		}
-		logging.Get().Infof("Finished Deploying %v", color.Magenta.Sprint(plan.Type))
-
-		if plan.Type == METADATA_PLAN_TYPE {
-			// Collect all app permission set UUIDs and datasource permissions from pliny so we can use them to create
-			// datasource permissions in warden. This is necessary because we need the UUIDs and the deploy plan only has the names
-			resultMap := plinyResult{}
-			err = json.Unmarshal(response, &resultMap)
-			if err != nil {
-				return
-			}
-			insertLen := len(resultMap.PermissionSets.Inserts)
-			updateLen := len(resultMap.PermissionSets.Updates)
-			dataPlan.AllPermissionSets = make([]PermissionSetResult, insertLen+updateLen)
-			i := 0
-			for _, v := range resultMap.PermissionSets.Inserts {
-				dataPlan.AllPermissionSets[i] = v
-				i++
-			}
-			for _, v := range resultMap.PermissionSets.Updates {
-				dataPlan.AllPermissionSets[i] = v
-				i++
-			}
-		}
+//
+// The stages aren't journaled to disk; see ExecuteDeployJournal for deploys that can be resumed
+func ExecuteDeployPlan(auth *Authorization, plans NlxDynamicPlanMap, targetDir string, variables util.Variables) (duration time.Duration, planResults []NlxDeploymentResult, err error) {
+	return ExecuteBatchedDeployPlan(auth, plans, targetDir, variables, 0)
+}
 
-		if plan.Type == DATA_PLAN_TYPE && len(plan.AllPermissionSets) > 0 {
-			// Create permission set datasource permissions with the UUIDs that the metadata service generated and assigned
-			newPsPlan := plan // we do not need a deep copy, as we are only changing the path
-			newPsPlan.Endpoint = "/metadata/update-permissionsets"
-			psurl := GenerateRoute(auth, newPsPlan)
-			var pspayload []byte
-			pay := newPsPlan.AllPermissionSets
-			pspayload, err = json.Marshal(pay)
-			if err != nil {
-				return
-			}
-			_, err = Request(
-				psurl,
-				http.MethodPost,
-				pspayload,
-				headers,
-			)
-			if err != nil {
-				return
-			}
-		}
+// ExecuteBatchedDeployPlan is ExecuteDeployPlan, except plans whose payload
+// is larger than maxPayloadSize bytes are deployed in batches. Once a batch
+// fails, the batches after it aren't deployed.
+func ExecuteBatchedDeployPlan(auth *Authorization, plans NlxDynamicPlanMap, targetDir string, variables util.Variables, maxPayloadSize int) (duration time.Duration, planResults []NlxDeploymentResult, err error) {
+	var batches map[string][]DeployBatch
+	if batches, err = PlanBatches(plans, targetDir, variables, maxPayloadSize); err != nil {
 		return
 	}
-
-	// Run metadata plan first, because there may be PermissionSet data to create
-	if mok {
-		err = executePlan(metaPlan)
-		if err != nil {
-			return
-		}
-	}
-	if dok {
-		err = executePlan(dataPlan)
-		if err != nil {
-			return
-		}
-	}
-
-	// Tell pliny to sync datasource external_id field with warden ids
-	if mok && dok {
-		syncPlan := metaPlan
-		headers := GeneratePlanHeaders(auth, syncPlan)
-		syncPlan.Endpoint = "/metadata/deploy/sync"
-		url := GenerateRoute(auth, syncPlan)
-		_, err = Request(
-			url,
-			http.MethodPost,
-			[]byte{},
-			headers,
-		)
-	}
-
-	return
+	journal := newDeployJournal(auth.Host, plans)
+	journal.Batch(batches)
+	return ExecuteDeployJournal(auth, journal, targetDir, variables)
 }
 
 type NlxDeploymentResult struct {
@@ -268,6 +152,9 @@ type NlxDeploymentResult struct {
 	PlanName string
 	Url      string
 	Data     []byte
+	// Batch is set when the plan was deployed in batches, and Plan only has
+	// the batch's metadata
+	Batch *DeployBatch
 }
 
 func (result NlxDeploymentResult) String() string {
@@ -41,7 +41,7 @@ func TestGetDeployPlan(t *testing.T) {
 	}
 	t.Log(duration)
 
-	duration, _, err = pkg.ExecuteDeployPlan(auth, plans, fp)
+	duration, _, err = pkg.ExecuteDeployPlan(auth, plans, fp, nil)
 	if err != nil {
 		t.Log(err)
 		t.FailNow()
@@ -59,6 +59,6 @@ func BenchmarkDeploymentPlan(b *testing.B) {
 	b.ResetTimer()
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		_, _, _ = pkg.ExecuteDeployPlan(auth, plans, fp)
+		_, _, _ = pkg.ExecuteDeployPlan(auth, plans, fp, nil)
 	}
 }
@@ -0,0 +1,328 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gookit/color"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+const (
+	DIFF_ADDED    = "added"
+	DIFF_REMOVED  = "removed"
+	DIFF_MODIFIED = "modified"
+
+	DIFF_CONTEXT_LINES = 3
+)
+
+// FileDiff is a single file that differs between two metadata directories
+type FileDiff struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Diff   string `json:"diff,omitempty"`
+	// Changes are the structural changes to a json file
+	Changes []util.JsonChange `json:"changes,omitempty"`
+}
+
+// EntityDiff groups the file differences for one metadata entity
+type EntityDiff struct {
+	Type   string     `json:"type"`
+	Name   string     `json:"name"`
+	Status string     `json:"status"`
+	Files  []FileDiff `json:"files"`
+}
+
+type DiffSummary struct {
+	Added    int `json:"added"`
+	Removed  int `json:"removed"`
+	Modified int `json:"modified"`
+}
+
+// DiffResult is the difference between the "from" metadata and the "to" metadata.
+// Entities only in "to" are added and entities only in "from" are removed.
+type DiffResult struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Summary  DiffSummary  `json:"summary"`
+	Entities []EntityDiff `json:"entities"`
+}
+
+// Empty returns true if there are no differences
+func (result DiffResult) Empty() bool {
+	return len(result.Entities) == 0
+}
+
+// FilterItem returns true if either service's plan includes the path
+func (plans NlxPlanPayload) FilterItem(item string) bool {
+	for _, plan := range []*NlxPlan{plans.MetadataService, plans.CloudDataService} {
+		if plan != nil && plan.Metadata.FilterItem(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataFiles returns the relative (forward slash) paths of every metadata
+// file in the directory that isn't hidden or ignored, mapped to their
+// location on disk
+func metadataFiles(directory string, keep func(string) bool) (files map[string]string, err error) {
+	var ignore *util.IgnoreMatcher
+	if ignore, err = util.LoadIgnore(directory); err != nil {
+		return
+	}
+
+	files = make(map[string]string)
+	err = filepath.Walk(directory, func(filePath string, fileInfo os.FileInfo, e error) (err error) {
+		if e != nil {
+			return e
+		}
+
+		var relativePath string
+		if relativePath, err = filepath.Rel(directory, filePath); err != nil {
+			return
+		}
+
+		if strings.HasPrefix(relativePath, ".") && relativePath != "." {
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return
+		}
+
+		if ignore.Ignored(relativePath, fileInfo.IsDir()) {
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return
+		}
+
+		if fileInfo.IsDir() {
+			return
+		}
+
+		if _, _, ok := EntityFromPath(relativePath); !ok {
+			return
+		}
+
+		if keep != nil && !keep(relativePath) {
+			return
+		}
+
+		files[filepath.ToSlash(relativePath)] = filePath
+		return
+	})
+	return
+}
+
+func readNormalized(relativePath, filePath string) (data []byte, err error) {
+	if data, err = os.ReadFile(filePath); err != nil {
+		return
+	}
+	data = util.NormalizeMetadataFile(relativePath, data)
+	return
+}
+
+// compareSemantically checks whether two files that differ as text are still
+// the same metadata: json with the same structure, or page xml that an xml
+// parser reads the same way. For json it also returns the structural changes.
+func compareSemantically(relativePath, fromPath, toPath string, fromData, toData []byte) (same bool, changes []util.JsonChange) {
+	metadataType := util.MetadataTypeFromArchivePath(relativePath)
+	switch strings.ToLower(filepath.Ext(relativePath)) {
+	case ".json":
+		if metadataType == "files" {
+			return
+		}
+		var err error
+		if changes, err = util.DiffJson(fromData, toData); err != nil {
+			return false, nil
+		}
+		same = len(changes) == 0
+	case ".xml":
+		if metadataType != "pages" {
+			return
+		}
+		// compare what we read from disk, in case either side
+		// couldn't be normalized
+		fromRaw, fromErr := os.ReadFile(fromPath)
+		toRaw, toErr := os.ReadFile(toPath)
+		if fromErr != nil || toErr != nil {
+			return
+		}
+		same, _ = util.XmlEquivalent(fromRaw, toRaw)
+	}
+	return
+}
+
+// UnifiedDiff returns a unified diff of two files
+func UnifiedDiff(relativePath string, from, to []byte) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(from)),
+		B:        difflib.SplitLines(string(to)),
+		FromFile: "a/" + relativePath,
+		ToFile:   "b/" + relativePath,
+		Context:  DIFF_CONTEXT_LINES,
+	})
+}
+
+// DiffDirectories compares the metadata in two directories after normalizing
+// both sides the same way retrieved metadata is normalized. keep decides which
+// relative paths are compared; a nil keep compares everything.
+func DiffDirectories(fromDir, toDir string, keep func(string) bool) (result DiffResult, err error) {
+	result = DiffResult{
+		From:     fromDir,
+		To:       toDir,
+		Entities: []EntityDiff{},
+	}
+
+	var fromFiles, toFiles map[string]string
+	if fromFiles, err = metadataFiles(fromDir, keep); err != nil {
+		return
+	}
+	if toFiles, err = metadataFiles(toDir, keep); err != nil {
+		return
+	}
+
+	paths := make([]string, 0, len(fromFiles)+len(toFiles))
+	for path := range fromFiles {
+		paths = append(paths, path)
+	}
+	for path := range toFiles {
+		if _, ok := fromFiles[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	entities := make(map[string]*EntityDiff)
+	var order []string
+
+	for _, path := range paths {
+		fromPath, inFrom := fromFiles[path]
+		toPath, inTo := toFiles[path]
+
+		fileDiff := FileDiff{Path: path}
+		switch {
+		case !inFrom:
+			fileDiff.Status = DIFF_ADDED
+		case !inTo:
+			fileDiff.Status = DIFF_REMOVED
+		default:
+			var fromData, toData []byte
+			if fromData, err = readNormalized(path, fromPath); err != nil {
+				return
+			}
+			if toData, err = readNormalized(path, toPath); err != nil {
+				return
+			}
+			if bytes.Equal(fromData, toData) {
+				continue
+			}
+			var same bool
+			if same, fileDiff.Changes = compareSemantically(path, fromPath, toPath, fromData, toData); same {
+				continue
+			}
+			fileDiff.Status = DIFF_MODIFIED
+			if fileDiff.Diff, err = UnifiedDiff(path, fromData, toData); err != nil {
+				return
+			}
+		}
+
+		logging.Get().Tracef("%v: %v", fileDiff.Status, color.Cyan.Sprint(path))
+
+		metadataType, name, _ := EntityFromPath(path)
+		key := entityKey(metadataType, name)
+		entity, ok := entities[key]
+		if !ok {
+			entity = &EntityDiff{Type: metadataType, Name: name}
+			entities[key] = entity
+			order = append(order, key)
+		}
+		entity.Files = append(entity.Files, fileDiff)
+	}
+
+	// an entity is only added or removed if none of its files
+	// exist on the other side
+	fromEntities := entityKeys(fromFiles)
+	toEntities := entityKeys(toFiles)
+
+	sort.Strings(order)
+	for _, key := range order {
+		entity := entities[key]
+		switch {
+		case !fromEntities[key]:
+			entity.Status = DIFF_ADDED
+			result.Summary.Added++
+		case !toEntities[key]:
+			entity.Status = DIFF_REMOVED
+			result.Summary.Removed++
+		default:
+			entity.Status = DIFF_MODIFIED
+			result.Summary.Modified++
+		}
+		result.Entities = append(result.Entities, *entity)
+	}
+
+	return
+}
+
+func entityKey(metadataType, name string) string {
+	return metadataType + "/" + name
+}
+
+func entityKeys(files map[string]string) (keys map[string]bool) {
+	keys = make(map[string]bool)
+	for path := range files {
+		metadataType, name, _ := EntityFromPath(path)
+		keys[entityKey(metadataType, name)] = true
+	}
+	return
+}
+
+// WriteText writes the differences grouped by metadata type, with the
+// structural changes to json files, followed by the unified diffs of
+// every modified file
+func (result DiffResult) WriteText(w io.Writer) (err error) {
+	fmt.Fprintf(w, "--- a: %v\n+++ b: %v\n", result.From, result.To)
+
+	var currentType string
+	for _, entity := range result.Entities {
+		if entity.Type != currentType {
+			currentType = entity.Type
+			fmt.Fprintf(w, "\n%v\n", entity.Type)
+		}
+		fmt.Fprintf(w, "  %-9v %v\n", entity.Status, entity.Name)
+		for _, file := range entity.Files {
+			for _, change := range file.Changes {
+				fmt.Fprintf(w, "              %v\n", change)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n%v added, %v removed, %v modified\n",
+		result.Summary.Added,
+		result.Summary.Removed,
+		result.Summary.Modified,
+	)
+
+	for _, entity := range result.Entities {
+		for _, file := range entity.Files {
+			if file.Diff == "" {
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "\n%v", file.Diff); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,121 @@
+package pkg_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestDiffDirectories(t *testing.T) {
+	remote := writeTestSite(t, map[string]string{
+		"pages/Home.json":                        `{"name":"Home","module":"Sales"}`,
+		"pages/Home.xml":                         "<skuid__page a=\"1\" b=\"2\">\n\t<models/>\n</skuid__page>",
+		"pages/Old.json":                         `{"name":"Old"}`,
+		"pages/Old.xml":                          `<skuid__page/>`,
+		"datasources/Orders.json":                "{\n\t\"name\": \"Orders\",\n\t\"url\": \"https://a\"\n}",
+		"componentpacks/charts/manifest.json":    `{"name":"charts"}`,
+		"componentpacks/charts/runtime.js":       `console.log(1)`,
+		"README.md":                              `not metadata`,
+		".git/config":                            `hidden`,
+		"themes/Dark.json":                       `{"name":"Dark"}`,
+		"themes/Dark.inline.css":                 `body {}`,
+		"connectionvariables/Orders-apikey.json": `{"name":"apikey"}`,
+	})
+	local := writeTestSite(t, map[string]string{
+		// same content, different formatting
+		"pages/Home.json":                        `{"module":"Sales","name":"Home"}`,
+		"pages/Home.xml":                         `<skuid__page b="2" a="1"><models></models></skuid__page>`,
+		"pages/New.json":                         `{"name":"New"}`,
+		"pages/New.xml":                          `<skuid__page/>`,
+		"datasources/Orders.json":                `{"url":"https://b","name":"Orders"}`,
+		"componentpacks/charts/manifest.json":    `{"name":"charts"}`,
+		"componentpacks/charts/runtime.js":       `console.log(2)`,
+		"README.md":                              `different, but not metadata`,
+		"themes/Dark.json":                       `{"name":"Dark"}`,
+		"connectionvariables/Orders-apikey.json": `{"name":"apikey"}`,
+	})
+
+	result, err := pkg.DiffDirectories(remote, local, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, pkg.DiffSummary{Added: 1, Removed: 1, Modified: 3}, result.Summary)
+
+	statuses := map[string]string{}
+	for _, entity := range result.Entities {
+		statuses[entity.Type+"/"+entity.Name] = entity.Status
+	}
+	assert.Equal(t, map[string]string{
+		"componentpacks/charts": pkg.DIFF_MODIFIED,
+		"datasources/Orders":    pkg.DIFF_MODIFIED,
+		"pages/New":             pkg.DIFF_ADDED,
+		"pages/Old":             pkg.DIFF_REMOVED,
+		"themes/Dark":           pkg.DIFF_MODIFIED,
+	}, statuses)
+
+	for _, entity := range result.Entities {
+		if entity.Name == "Orders" {
+			if assert.Len(t, entity.Files, 1) {
+				assert.Contains(t, entity.Files[0].Diff, "--- a/datasources/Orders.json")
+				assert.Contains(t, entity.Files[0].Diff, "-\t\"url\": \"https://a\"")
+				assert.Contains(t, entity.Files[0].Diff, "+\t\"url\": \"https://b\"")
+			}
+		}
+		if entity.Name == "Dark" {
+			// the theme exists on both sides, but lost its inline css
+			if assert.Len(t, entity.Files, 1) {
+				assert.Equal(t, pkg.FileDiff{Path: "themes/Dark.inline.css", Status: pkg.DIFF_REMOVED}, entity.Files[0])
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, result.WriteText(&out))
+	assert.Contains(t, out.String(), "\npages\n  added     New\n  removed   Old\n")
+	assert.Contains(t, out.String(), "\ndatasources\n  modified  Orders\n              ~ url: \"https://a\" => \"https://b\"\n")
+	assert.Contains(t, out.String(), "1 added, 1 removed, 3 modified")
+	assert.Equal(t, 2, strings.Count(out.String(), "@@ -1"))
+}
+
+func TestDiffDirectoriesKeep(t *testing.T) {
+	remote := writeTestSite(t, map[string]string{
+		"pages/Home.json": `{"name":"Home"}`,
+	})
+	local := writeTestSite(t, map[string]string{
+		"pages/Home.json":  `{"name":"Home"}`,
+		"pages/Other.json": `{"name":"Other"}`,
+	})
+
+	plans := pkg.NlxPlanPayload{
+		MetadataService: &pkg.NlxPlan{Metadata: pkg.NlxMetadata{Pages: []string{"Home"}}},
+	}
+
+	result, err := pkg.DiffDirectories(remote, local, plans.FilterItem)
+	assert.NoError(t, err)
+	assert.True(t, result.Empty())
+
+	result, err = pkg.DiffDirectories(remote, local, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Summary.Added)
+}
+
+func TestDiffDirectoriesReordered(t *testing.T) {
+	remote := writeTestSite(t, map[string]string{
+		"apps/Sales.json": `{"name":"Sales","tabs":[{"name":"Orders"},{"name":"Accounts"}]}`,
+	})
+	local := writeTestSite(t, map[string]string{
+		"apps/Sales.json": `{"name":"Sales","tabs":[{"name":"Accounts"},{"name":"Orders"}]}`,
+	})
+
+	// the same tabs in another order are a change
+	result, err := pkg.DiffDirectories(remote, local, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.DiffSummary{Modified: 1}, result.Summary)
+
+	var out bytes.Buffer
+	assert.NoError(t, result.WriteText(&out))
+	assert.Contains(t, out.String(), `~ tabs: ["Orders","Accounts"] => ["Accounts","Orders"]`)
+}
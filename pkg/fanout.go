@@ -0,0 +1,238 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// DeployTarget is one of the sites a deploy goes to
+type DeployTarget struct {
+	Host string
+	// Profile is nil when no profile was given
+	Profile *Profile
+}
+
+// ProfileName returns the name of the target's profile, if it has one
+func (target DeployTarget) ProfileName() string {
+	if target.Profile == nil {
+		return ""
+	}
+	return target.Profile.Name
+}
+
+// Protected returns true if the target's profile is protected, or its host is
+// any protected profile's host
+func (target DeployTarget) Protected() bool {
+	return (target.Profile != nil && target.Profile.Protected) || ProtectedHost(target.Host)
+}
+
+// DeployTargets decides which sites to deploy to. With several profiles,
+// each profile's host is deployed to with its settings. Otherwise each host
+// is deployed to with the profile, if there is one, falling back to the
+// profile's host. Empty hosts are ignored.
+func DeployTargets(hosts []string, profiles []Profile) (targets []DeployTarget, err error) {
+	var given []string
+	for _, host := range hosts {
+		if host = strings.TrimSpace(host); host != "" {
+			given = append(given, host)
+		}
+	}
+	hosts = given
+
+	if len(profiles) > 1 {
+		if len(hosts) > 0 {
+			err = fmt.Errorf("--host can't be used with more than one --profile, set each profile's host instead")
+			return
+		}
+		for i := range profiles {
+			if profiles[i].Host == "" {
+				err = fmt.Errorf("profile %v has no host", profiles[i].Name)
+				return
+			}
+			targets = append(targets, DeployTarget{Host: profiles[i].Host, Profile: &profiles[i]})
+		}
+	} else {
+		var profile *Profile
+		if len(profiles) == 1 {
+			profile = &profiles[0]
+			if len(hosts) == 0 && profile.Host != "" {
+				hosts = []string{profile.Host}
+			}
+		}
+		if len(hosts) == 0 {
+			err = fmt.Errorf(`required flag(s) "host" not set`)
+			return
+		}
+		for _, host := range hosts {
+			targets = append(targets, DeployTarget{Host: host, Profile: profile})
+		}
+	}
+
+	for i := range targets {
+		for j := 0; j < i; j++ {
+			if sameHost(targets[i].Host, targets[j].Host) {
+				err = fmt.Errorf("%v is deployed to more than once", targets[i].Host)
+				return
+			}
+		}
+	}
+	return
+}
+
+// SiteDeployReport is how the deploy to one site went
+type SiteDeployReport struct {
+	Host    string `json:"host"`
+	Profile string `json:"profile,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	// Results are missing for sites that failed before deploying
+	Results *DeployResultsReport `json:"results,omitempty"`
+}
+
+// MultiSiteDeployReport is how the deploy to each of several sites went
+type MultiSiteDeployReport struct {
+	Sites []SiteDeployReport `json:"sites"`
+}
+
+// DeploySites deploys to each target, with at most parallelism deploys at a
+// time. A failed site doesn't stop the others, unless failFast is set, in
+// which case the sites that haven't started yet are skipped. Sites are
+// reported in target order.
+func DeploySites(targets []DeployTarget, parallelism int, failFast bool, deploy func(DeployTarget) (*DeployResultsReport, error)) (report MultiSiteDeployReport) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	report.Sites = make([]SiteDeployReport, len(targets))
+	for i, target := range targets {
+		report.Sites[i] = SiteDeployReport{
+			Host:    target.Host,
+			Profile: target.ProfileName(),
+			Status:  DEPLOY_STATUS_SKIPPED,
+		}
+	}
+
+	var wg sync.WaitGroup
+	var failedMutex sync.Mutex
+	failed := false
+	slots := make(chan struct{}, parallelism)
+
+	for i, target := range targets {
+		slots <- struct{}{}
+
+		failedMutex.Lock()
+		stop := failFast && failed
+		failedMutex.Unlock()
+		if stop {
+			<-slots
+			report.Sites[i].Error = "not deployed because another site failed"
+			continue
+		}
+
+		wg.Add(1)
+		go func(site *SiteDeployReport, target DeployTarget) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			results, err := deploy(target)
+			site.Results = results
+			if err != nil {
+				site.Status = DEPLOY_STATUS_FAILED
+				site.Error = err.Error()
+				failedMutex.Lock()
+				failed = true
+				failedMutex.Unlock()
+				return
+			}
+			site.Status = DEPLOY_STATUS_DEPLOYED
+		}(&report.Sites[i], target)
+	}
+
+	wg.Wait()
+	return
+}
+
+// Err returns an error if any site wasn't deployed
+func (report MultiSiteDeployReport) Err() error {
+	var failed, skipped int
+	for _, site := range report.Sites {
+		switch site.Status {
+		case DEPLOY_STATUS_FAILED:
+			failed++
+		case DEPLOY_STATUS_SKIPPED:
+			skipped++
+		}
+	}
+	switch {
+	case skipped > 0:
+		return fmt.Errorf("%v of %v site(s) failed and %v skipped", failed, len(report.Sites), skipped)
+	case failed > 0:
+		return fmt.Errorf("%v of %v site(s) failed", failed, len(report.Sites))
+	}
+	return nil
+}
+
+// WriteTable writes each site's status and entity counts
+func (report MultiSiteDeployReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "SITE\tPROFILE\tSTATUS\tINSERTED\tUPDATED\tDELETED\tUNCHANGED\tERROR")
+	for _, site := range report.Sites {
+		var inserted, updated, deleted, unchanged int
+		if site.Results != nil {
+			inserted, updated, deleted, unchanged = site.Results.Totals()
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			site.Host,
+			site.Profile,
+			site.Status,
+			inserted,
+			updated,
+			deleted,
+			unchanged,
+			strings.ReplaceAll(site.Error, "\n", " "),
+		)
+	}
+
+	return tw.Flush()
+}
+
+// WriteJUnit writes the report as JUnit XML, with a test suite for each
+// service of each site. A site that failed or was skipped before deploying
+// is a single failed or skipped test case.
+func (report MultiSiteDeployReport) WriteJUnit(w io.Writer) error {
+	suites := junitTestSuites{Name: "deploy"}
+
+	for _, site := range report.Sites {
+		if site.Results != nil {
+			suites.add(site.Results.junitSuites(site.Host + " ")...)
+			continue
+		}
+
+		suite := junitTestSuite{Name: site.Host, Tests: 1}
+		testCase := junitTestCase{ClassName: site.Host, Name: "deploy"}
+		switch site.Status {
+		case DEPLOY_STATUS_FAILED:
+			testCase.Failure = &junitMessage{Message: site.Error}
+			suite.Failures++
+		case DEPLOY_STATUS_SKIPPED:
+			testCase.Skipped = &junitMessage{Message: site.Error}
+			suite.Skipped++
+		}
+		suite.Cases = []junitTestCase{testCase}
+		suites.add(suite)
+	}
+
+	return suites.write(w)
+}
+
+// WriteFile writes the report to a file, as JUnit XML if the file name ends
+// in .xml and as json otherwise
+func (report MultiSiteDeployReport) WriteFile(path string) error {
+	return writeReportFile(path, report, report.WriteJUnit)
+}
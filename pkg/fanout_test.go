@@ -0,0 +1,180 @@
+package pkg_test
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestDeployTargets(t *testing.T) {
+	prod := pkg.Profile{Name: "prod", Host: "https://prod.skuidsite.com", Protected: true}
+	uat := pkg.Profile{Name: "uat", Host: "https://uat.skuidsite.com"}
+	noHost := pkg.Profile{Name: "nohost"}
+
+	for _, tc := range []struct {
+		description   string
+		hosts         []string
+		profiles      []pkg.Profile
+		expected      []string
+		expectedError string
+	}{
+		{
+			description: "one host",
+			hosts:       []string{"a.skuidsite.com"},
+			expected:    []string{"a.skuidsite.com"},
+		},
+		{
+			description: "several hosts with a profile",
+			hosts:       []string{"a.skuidsite.com", "", "b.skuidsite.com"},
+			profiles:    []pkg.Profile{uat},
+			expected:    []string{"a.skuidsite.com", "b.skuidsite.com"},
+		},
+		{
+			description: "profile host",
+			profiles:    []pkg.Profile{prod},
+			expected:    []string{"https://prod.skuidsite.com"},
+		},
+		{
+			description: "host overrides profile host",
+			hosts:       []string{"a.skuidsite.com"},
+			profiles:    []pkg.Profile{prod},
+			expected:    []string{"a.skuidsite.com"},
+		},
+		{
+			description: "several profiles",
+			profiles:    []pkg.Profile{prod, uat},
+			expected:    []string{"https://prod.skuidsite.com", "https://uat.skuidsite.com"},
+		},
+		{
+			description:   "no host",
+			profiles:      []pkg.Profile{noHost},
+			expectedError: `required flag(s) "host" not set`,
+		},
+		{
+			description:   "several profiles without a host",
+			profiles:      []pkg.Profile{prod, noHost},
+			expectedError: "profile nohost has no host",
+		},
+		{
+			description:   "several profiles and hosts",
+			hosts:         []string{"a.skuidsite.com"},
+			profiles:      []pkg.Profile{prod, uat},
+			expectedError: "--host can't be used with more than one --profile, set each profile's host instead",
+		},
+		{
+			description:   "same host twice",
+			hosts:         []string{"a.skuidsite.com", "https://a.skuidsite.com/"},
+			expectedError: "https://a.skuidsite.com/ is deployed to more than once",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			targets, err := pkg.DeployTargets(tc.hosts, tc.profiles)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			var hosts []string
+			for _, target := range targets {
+				hosts = append(hosts, target.Host)
+			}
+			assert.Equal(t, tc.expected, hosts)
+		})
+	}
+
+	targets, err := pkg.DeployTargets(nil, []pkg.Profile{prod, uat})
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", targets[0].ProfileName())
+	assert.True(t, targets[0].Protected())
+	assert.False(t, targets[1].Protected())
+
+	// a protected profile's host is protected without the profile
+	viper.Set("profiles", map[string]interface{}{
+		"prod": map[string]interface{}{"host": prod.Host, "protected": true},
+		"uat":  map[string]interface{}{"host": uat.Host},
+	})
+	defer viper.Reset()
+	for _, tc := range []struct {
+		host     string
+		expected bool
+	}{
+		{host: "https://prod.skuidsite.com", expected: true},
+		{host: "PROD.skuidsite.com/", expected: true},
+		{host: uat.Host, expected: false},
+		{host: "https://other.skuidsite.com", expected: false},
+	} {
+		assert.Equal(t, tc.expected, pkg.DeployTarget{Host: tc.host}.Protected(), tc.host)
+		assert.Equal(t, tc.expected, pkg.DeployTarget{Host: tc.host, Profile: &uat}.Protected(), tc.host)
+	}
+}
+
+func TestDeploySites(t *testing.T) {
+	targets := []pkg.DeployTarget{{Host: "a"}, {Host: "b"}, {Host: "c"}, {Host: "d"}}
+	deploy := func(target pkg.DeployTarget) (*pkg.DeployResultsReport, error) {
+		if target.Host == "b" {
+			return nil, errors.New("unauthorized")
+		}
+		return &pkg.DeployResultsReport{Host: target.Host}, nil
+	}
+
+	// a failed site doesn't stop the others
+	report := pkg.DeploySites(targets, 2, false, deploy)
+	var statuses []string
+	for _, site := range report.Sites {
+		statuses = append(statuses, site.Host+" "+site.Status)
+	}
+	assert.Equal(t, []string{"a deployed", "b failed", "c deployed", "d deployed"}, statuses)
+	assert.Equal(t, "unauthorized", report.Sites[1].Error)
+	assert.EqualError(t, report.Err(), "1 of 4 site(s) failed")
+
+	// unless failing fast
+	report = pkg.DeploySites(targets, 1, true, deploy)
+	statuses = nil
+	for _, site := range report.Sites {
+		statuses = append(statuses, site.Host+" "+site.Status)
+	}
+	assert.Equal(t, []string{"a deployed", "b failed", "c skipped", "d skipped"}, statuses)
+	assert.EqualError(t, report.Err(), "1 of 4 site(s) failed and 2 skipped")
+
+	var out bytes.Buffer
+	assert.NoError(t, report.WriteTable(&out))
+	assert.Regexp(t, `b\s+failed\s+0\s+0\s+0\s+0\s+unauthorized`, out.String())
+	assert.Regexp(t, `c\s+skipped\s+0\s+0\s+0\s+0\s+not deployed because another site failed`, out.String())
+
+	out.Reset()
+	assert.NoError(t, report.WriteJUnit(&out))
+	assert.Contains(t, out.String(), `<testsuite name="b" tests="1" failures="1" skipped="0">`)
+	assert.Contains(t, out.String(), `<failure message="unauthorized"></failure>`)
+	assert.Contains(t, out.String(), `<testsuite name="c" tests="1" failures="0" skipped="1">`)
+}
+
+func TestDeploySitesParallelism(t *testing.T) {
+	var mutex sync.Mutex
+	running, most := 0, 0
+	targets := []pkg.DeployTarget{{Host: "a"}, {Host: "b"}, {Host: "c"}, {Host: "d"}, {Host: "e"}}
+
+	report := pkg.DeploySites(targets, 2, false, func(target pkg.DeployTarget) (*pkg.DeployResultsReport, error) {
+		mutex.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mutex.Unlock()
+
+		defer func() {
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+		}()
+		return &pkg.DeployResultsReport{Host: target.Host}, nil
+	})
+
+	assert.NoError(t, report.Err())
+	assert.LessOrEqual(t, most, 2)
+}
@@ -20,7 +20,7 @@ var (
 
 	NoModule = &Flag[bool]{
 		Name:  "no-module",
-		Usage: "Retrieve only those pages that do not have a module",
+		Usage: "Only include pages that do not have a module",
 	}
 
 	FileLogging = &Flag[bool]{
@@ -50,4 +50,86 @@ var (
 		Usage:       "Force deployment by ignoring package compatibility check",
 		EnvVarNames: []string{constants.SKUID_IGNORE_COMPATIBILITY_CHECK},
 	}
+
+	PlanOnly = &Flag[bool]{
+		Name:  "plan-only",
+		Usage: "Print the retrieval plan and exit without retrieving any metadata",
+	}
+
+	KeepVolatileFields = &Flag[bool]{
+		Name:  "keep-volatile-fields",
+		Usage: "Keep fields that change on every retrieve (timestamps, modified by, etc.) instead of removing them",
+	}
+
+	DryRun = &Flag[bool]{
+		Name:  "dry-run",
+		Usage: "Build the deployment and request its plan, then print a report instead of deploying",
+	}
+
+	FailOnWarnings = &Flag[bool]{
+		Name:  "fail-on-warnings",
+		Usage: "Exit with an error before deploying if the deployment plan has warnings that the config file doesn't allow",
+	}
+
+	Snapshot = &Flag[bool]{
+		Name:        "snapshot",
+		Usage:       "Retrieve the current version of everything being deployed into .skuid/snapshots before deploying, so the deploy can be rolled back",
+		EnvVarNames: []string{constants.ENV_SKUID_SNAPSHOT},
+	}
+
+	Yes = &Flag[bool]{
+		Name:      "yes",
+		Shorthand: "y",
+		Usage:     "Deploy without asking for confirmation",
+	}
+
+	FailFast = &Flag[bool]{
+		Name:  "fail-fast",
+		Usage: "When deploying to several sites, stop starting deploys once one site fails",
+	}
+
+	Resume = &Flag[bool]{
+		Name:  "resume",
+		Usage: "Continue the last deploy of the directory from the first stage that didn't complete, using its saved plan",
+	}
+
+	ListSnapshots = &Flag[bool]{
+		Name:  "list",
+		Usage: "List the snapshots in the directory instead of rolling back",
+	}
+
+	PreviewDiff = &Flag[bool]{
+		Name:  "diff",
+		Usage: "Retrieve the target site too and print what would change before deploying",
+	}
+
+	Checksum = &Flag[bool]{
+		Name:  "checksum",
+		Usage: "Print a checksum of the archived files' names and contents, which is the same for the same files on any machine",
+	}
+
+	ShowIgnored = &Flag[bool]{
+		Name:  "show-ignored",
+		Usage: "List the files in the directory that .skuidignore excludes",
+	}
+
+	OnlyChanged = &Flag[bool]{
+		Name:  "only-changed",
+		Usage: "Only deploy the metadata that changed since it was last successfully deployed to the site, according to the directory's deploy manifest",
+	}
+
+	SkipValidation = &Flag[bool]{
+		Name:  "skip-validation",
+		Usage: "Deploy without first checking that the files parse, have their companion files and refer to entities that exist",
+	}
+
+	Prune = &Flag[bool]{
+		Name:  "prune",
+		Usage: "With --dry-run, list the entities on the site that the directory doesn't have, within the app, page, module and type filters",
+	}
+
+	Force = &Flag[bool]{
+		Name:  "force",
+		Usage: "Deploy all of the metadata even with --only-changed, ignoring the deploy manifest",
+	}
 )
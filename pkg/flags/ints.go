@@ -0,0 +1,19 @@
+package flags
+
+import (
+	"github.com/skuid/skuid-cli/pkg/constants"
+)
+
+var (
+	Parallelism = &Flag[int]{
+		Name:    "parallelism",
+		Usage:   "How many sites to deploy to at once when deploying to several",
+		Default: 4,
+	}
+
+	MaxPayloadSize = &Flag[int]{
+		Name:        "max-payload-size",
+		Usage:       "Deploy each service's metadata in batches when its payload is larger than this many megabytes, 0 to never split it",
+		EnvVarNames: []string{constants.ENV_SKUID_MAX_PAYLOAD_SIZE},
+	}
+)
@@ -1,5 +1,9 @@
 package flags
 
+import (
+	"github.com/skuid/skuid-cli/pkg/constants"
+)
+
 var (
 	Modules = &Flag[[]string]{
 		Name:      "modules",
@@ -12,4 +16,31 @@ var (
 		Shorthand: "n",
 		Usage:     "Page name(s), separated by a comma",
 	}
+
+	Types = &Flag[[]string]{
+		Name:  "types",
+		Usage: "Only these metadata type(s), e.g. pages,apps, separated by a comma",
+	}
+
+	Report = &Flag[[]string]{
+		Name:  "report",
+		Usage: "File(s) to write the deployment results to, as JUnit XML for .xml files and JSON otherwise",
+	}
+
+	Hosts = &Flag[[]string]{
+		Name:        "host",
+		Usage:       `Host URL(s), e.g. [ https://my.skuidsite.com | my.skuidsite.com ]. Repeat to deploy to several sites at once`,
+		EnvVarNames: []string{constants.ENV_SKUID_HOST, constants.ENV_PLINY_HOST},
+	}
+
+	Profile = &Flag[[]string]{
+		Name:        "profile",
+		Usage:       "Name of a profile in the .skuid config file to read settings, such as deploy variables, from. Deploy can be given several, to deploy to each profile's host",
+		EnvVarNames: []string{constants.ENV_SKUID_PROFILE},
+	}
+
+	EnvFiles = &Flag[[]string]{
+		Name:  "env-file",
+		Usage: "Env file(s) with values for ${SKUID_ENV:NAME} placeholders, overriding the profile's variables",
+	}
 )
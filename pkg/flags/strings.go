@@ -57,4 +57,39 @@ var (
 		Usage:       "Timestamp or time span specifying only updated records to retrieve. Suggested timestamp format is: \"yyyy-MM-dd HH:mm AM\" or \"HH:mm AM\". Valid timespans look like various combination of \"1y2M3d8h30m\" or \"3 days\"",
 		EnvVarNames: []string{constants.ENV_SKUID_RETRIEVE_SINCE},
 	}
+
+	Output = &Flag[string]{
+		Name:      "output",
+		Shorthand: "o",
+		Usage:     "Output format, one of [ table | json | yaml ]",
+		Default:   "table",
+	}
+
+	PlanOut = &Flag[string]{
+		Name:     "out",
+		Usage:    "File to save the deployment plan to, e.g. plan.skuidplan",
+		Required: true,
+	}
+
+	ArchiveOut = &Flag[string]{
+		Name:  "out",
+		Usage: "File to write the archive to, e.g. site.zip",
+	}
+
+	ChangedSince = &Flag[string]{
+		Name:  "changed-since",
+		Usage: "Only deploy the metadata with files that changed since a git ref, e.g. origin/main",
+	}
+
+	PromoteFrom = &Flag[string]{
+		Name:     "from",
+		Usage:    "Profile name or host URL of the site to promote metadata from",
+		Required: true,
+	}
+
+	PromoteTo = &Flag[string]{
+		Name:     "to",
+		Usage:    "Profile name or host URL of the site to promote metadata to",
+		Required: true,
+	}
 )
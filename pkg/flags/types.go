@@ -194,8 +194,9 @@ func Add[T any](flag *Flag[T]) func(*cobra.Command) error {
 			if len(flag.EnvVarNames) > 0 {
 				usageText = environmentVariablePossible(flag.EnvVarNames, flag.Usage)
 				for _, envVarName := range flag.EnvVarNames {
-					defaultVar = strings.Split(os.Getenv(envVarName), ",")
-					if len(defaultVar) > 0 {
+					envValue := os.Getenv(envVarName)
+					if envValue != "" {
+						defaultVar = strings.Split(envValue, ",")
 						usageText = environmentVariableFound(envVarName, flag.Usage)
 						// the only time we disabled required
 						// is when we have the environment variable name
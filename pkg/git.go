@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/gookit/color"
+
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// ChangedEntities are the metadata entities whose files changed since a git ref
+type ChangedEntities struct {
+	// Changed entities were added or modified, and still have files
+	Changed NlxMetadata `json:"changed"`
+	// Deleted entities no longer have any files, so a deploy can't include them
+	Deleted NlxMetadata `json:"deleted"`
+}
+
+// runGit runs git in the directory and returns its output
+func runGit(dir string, args ...string) (output []byte, err error) {
+	command := exec.Command("git", args...)
+	command.Dir = dir
+
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+
+	logging.Get().Tracef("Running %v", color.Gray.Sprintf("git %v", strings.Join(args, " ")))
+	if output, err = command.Output(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			err = fmt.Errorf("git %v failed: %v", args[0], message)
+		} else {
+			err = fmt.Errorf("git %v failed: %w", args[0], err)
+		}
+	}
+	return
+}
+
+// GitChangedFiles returns the files in the directory that are different from
+// the ref, relative to the directory, including untracked files. Renames are
+// a deleted file and a changed file. Paths are read NUL separated, so that
+// git doesn't quote the ones with unusual characters.
+func GitChangedFiles(dir, ref string) (changed, deleted []string, err error) {
+	var output []byte
+	if output, err = runGit(dir, "diff", "--name-status", "--no-renames", "--relative", "-z", ref, "--"); err != nil {
+		return
+	}
+
+	// each file is its status, then its path
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, path := fields[i], fields[i+1]
+		if strings.HasPrefix(status, "D") {
+			deleted = append(deleted, path)
+		} else {
+			changed = append(changed, path)
+		}
+	}
+
+	if output, err = runGit(dir, "ls-files", "--others", "--exclude-standard", "-z"); err != nil {
+		return
+	}
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			changed = append(changed, path)
+		}
+	}
+
+	sort.Strings(changed)
+	sort.Strings(deleted)
+	return
+}
+
+// ChangedEntitiesFromFiles maps changed and deleted files in the directory to
+// their entities, skipping ignored files. An entity that lost some of its
+// files but not all of them (e.g. a theme's inline css) is changed, not deleted.
+func ChangedEntitiesFromFiles(dir string, changed, deleted []string) (entities ChangedEntities, err error) {
+	var files map[string]string
+	if files, err = metadataFiles(dir, nil); err != nil {
+		return
+	}
+
+	// ignored files never change what's deployed
+	var ignore *util.IgnoreMatcher
+	if ignore, err = util.LoadIgnore(dir); err != nil {
+		return
+	}
+	unignored := func(paths []string) (kept []string) {
+		for _, path := range paths {
+			if !ignore.Ignored(path, false) {
+				kept = append(kept, path)
+			}
+		}
+		return
+	}
+	changed, deleted = unignored(changed), unignored(deleted)
+
+	existing := make([]string, 0, len(files))
+	for path := range files {
+		existing = append(existing, path)
+	}
+	existingEntities := MetadataFromPaths(existing)
+
+	deletedEntities := MetadataFromPaths(deleted)
+	entities.Changed = MetadataFromPaths(changed).Union(deletedEntities.Intersect(existingEntities))
+	entities.Deleted = deletedEntities.Difference(existingEntities)
+	return
+}
+
+// GetChangedEntities returns the entities in the directory that changed since the git ref
+func GetChangedEntities(dir, ref string) (entities ChangedEntities, err error) {
+	var changed, deleted []string
+	if changed, deleted, err = GitChangedFiles(dir, ref); err != nil {
+		return
+	}
+	return ChangedEntitiesFromFiles(dir, changed, deleted)
+}
+
+// Warnings describes each deleted entity
+func (entities ChangedEntities) Warnings() (warnings []string) {
+	for _, metadataType := range GetMetadataTypeDirNames() {
+		names, _ := entities.Deleted.GetFieldValueByName(metadataType)
+		for _, name := range names {
+			warnings = append(warnings, fmt.Sprintf("%v %v was deleted locally and will not be deleted from the site", metadataType, name))
+		}
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package pkg_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	command := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	command.Dir = dir
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+func TestGetChangedEntities(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := writeTestSite(t, map[string]string{
+		"README.md":                                "docs",
+		"site/pages/Home.json":                     `{"name":"Home"}`,
+		"site/pages/Home.xml":                      `<skuid__page/>`,
+		"site/pages/Café.json":                     `{"name":"Café"}`,
+		"site/pages/Café.xml":                      `<skuid__page/>`,
+		"site/pages/Old.json":                      `{"name":"Old"}`,
+		"site/pages/Old.xml":                       `<skuid__page/>`,
+		"site/themes/Dark.json":                    `{"name":"Dark"}`,
+		"site/themes/Dark.inline.css":              `body {}`,
+		"site/componentpacks/charts/runtime.js":    `1`,
+		"site/connectionvariables/Orders-key.json": `{"name":"key"}`,
+		"site/tables/Accounts.json":                `{"name":"Accounts"}`,
+		"site/tables/Accounts.csv":                 `a,b`,
+	})
+	git(t, repo, "init", "-q")
+	git(t, repo, "add", "-A")
+	git(t, repo, "commit", "-q", "-m", "initial")
+
+	site := filepath.Join(repo, "site")
+	for path, body := range map[string]string{
+		"pages/Home.xml":                      `<skuid__page a="1"/>`,
+		"componentpacks/charts/runtime.js":    `2`,
+		"connectionvariables/Orders-key.json": `{"name":"key","value":1}`,
+		"tables/Accounts.csv":                 `a,b,c`,
+		"pages/New.json":                      `{"name":"New"}`,
+		"pages/Café.xml":                      `<skuid__page a="1"/>`,
+		"pages/Año Nuevo.json":                `{"name":"Año Nuevo"}`,
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(site, filepath.FromSlash(path)), []byte(body), 0644))
+	}
+	for _, path := range []string{"pages/Old.json", "pages/Old.xml", "themes/Dark.inline.css"} {
+		assert.NoError(t, os.Remove(filepath.Join(site, filepath.FromSlash(path))))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(repo, "README.md"), []byte("changed"), 0644))
+
+	changed, deleted, err := pkg.GitChangedFiles(site, "HEAD")
+	assert.NoError(t, err)
+	// git would quote the paths that aren't plain ascii
+	assert.Equal(t, []string{
+		"componentpacks/charts/runtime.js",
+		"connectionvariables/Orders-key.json",
+		"pages/Año Nuevo.json",
+		"pages/Café.xml",
+		"pages/Home.xml",
+		"pages/New.json",
+		"tables/Accounts.csv",
+	}, changed)
+	assert.Equal(t, []string{"pages/Old.json", "pages/Old.xml", "themes/Dark.inline.css"}, deleted)
+
+	entities, err := pkg.GetChangedEntities(site, "HEAD")
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.NlxMetadata{
+		ComponentPacks:      []string{"charts"},
+		ConnectionVariables: []string{"key"},
+		Pages:               []string{"Año Nuevo", "Café", "Home", "New"},
+		Themes:              []string{"Dark"},
+		Tables:              []string{"Accounts"},
+	}, entities.Changed)
+	assert.Equal(t, pkg.NlxMetadata{Pages: []string{"Old"}}, entities.Deleted)
+	assert.Equal(t, []string{"pages Old was deleted locally and will not be deleted from the site"}, entities.Warnings())
+
+	// the changed entities select all of their files, companions included
+	for _, path := range []string{"pages/Home.json", "pages/Home.xml", "tables/Accounts.json", "tables/Accounts.csv", "componentpacks/charts/runtime.js", "connectionvariables/Orders-key.json", "themes/Dark.json"} {
+		assert.True(t, entities.Changed.FilterItem(path), path)
+	}
+	assert.False(t, entities.Changed.FilterItem("pages/Other.json"))
+
+	_, _, err = pkg.GitChangedFiles(site, "no-such-ref")
+	assert.Error(t, err)
+}
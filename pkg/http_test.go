@@ -3,6 +3,7 @@ package pkg_test
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,6 +12,19 @@ import (
 	"github.com/skuid/skuid-cli/pkg/util"
 )
 
+// testSite serves the handler over TLS, since requests are always made with
+// https, and returns an authorization for a site at its address
+func testSite(t *testing.T, handler http.HandlerFunc) *pkg.Authorization {
+	server := httptest.NewTLSServer(handler)
+	transport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+	t.Cleanup(func() {
+		http.DefaultTransport = transport
+		server.Close()
+	})
+	return &pkg.Authorization{Host: server.URL}
+}
+
 func TestHttpMethods(t *testing.T) {
 	util.SkipIntegrationTest(t)
 	const YES_NO_API = "yesno.wtf/api"
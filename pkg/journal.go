@@ -0,0 +1,473 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+
+	"github.com/skuid/skuid-cli/pkg/constants"
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+const (
+	// DEPLOY_JOURNAL_FILE is relative to the deployed directory
+	DEPLOY_JOURNAL_FILE = ".skuid/deploy-journal.json"
+
+	DEPLOY_STAGE_METADATA        = "metadata"
+	DEPLOY_STAGE_DATA            = "data"
+	DEPLOY_STAGE_PERMISSION_SETS = "permissionSets"
+	DEPLOY_STAGE_SYNC            = "sync"
+
+	DEPLOY_STAGE_PENDING = "pending"
+	DEPLOY_STAGE_APPLIED = "applied"
+	DEPLOY_STAGE_FAILED  = "failed"
+
+	updatePermissionSetsEndpoint = "/metadata/update-permissionsets"
+	deploySyncEndpoint           = "/metadata/deploy/sync"
+)
+
+// DeployStage is one request of a deploy. Applied stages are never run again.
+type DeployStage struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Completed   *time.Time `json:"completed,omitempty"`
+	Url         string     `json:"url,omitempty"`
+	// Response is kept for the stages that later stages (and the
+	// results report) read
+	Response []byte `json:"response,omitempty"`
+	// Batch is the part of the plan the stage deploys, when the plan is
+	// deployed in batches
+	Batch *DeployBatch `json:"batch,omitempty"`
+	// Hashes are the sha256 of every file in the stage's archive, by path.
+	// They're recorded before any stage runs, so that a resumed deploy
+	// deploys exactly what the deploy started with.
+	Hashes map[string]string `json:"hashes,omitempty"`
+}
+
+// DeployStageError is the error of the stage of a deploy that failed
+type DeployStageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *DeployStageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeployStageError) Unwrap() error {
+	return e.Err
+}
+
+// failedStage returns the stage of a deploy that the error failed, if it
+// came from one
+func failedStage(err error) string {
+	var stageErr *DeployStageError
+	if errors.As(err, &stageErr) {
+		return stageErr.Stage
+	}
+	return ""
+}
+
+// DeployJournal records the plan of a deploy and the completion of each of
+// its stages, so that a deploy that failed part way can be resumed
+type DeployJournal struct {
+	Host    string            `json:"host"`
+	Started time.Time         `json:"started"`
+	Updated time.Time         `json:"updated"`
+	Plans   NlxDynamicPlanMap `json:"plans"`
+	Stages  []DeployStage     `json:"stages"`
+
+	// path is empty for journals that are only kept in memory
+	path string
+}
+
+// DeployJournalPath returns where the journal for a deployed directory is kept
+func DeployJournalPath(targetDir string) string {
+	return filepath.Join(targetDir, filepath.FromSlash(DEPLOY_JOURNAL_FILE))
+}
+
+func newDeployJournal(host string, plans NlxDynamicPlanMap) *DeployJournal {
+	now := time.Now().UTC()
+	journal := &DeployJournal{
+		Host:    host,
+		Started: now,
+		Updated: now,
+		Plans:   plans,
+	}
+
+	_, mok := plans[METADATA_PLAN_KEY]
+	_, dok := plans[DATA_PLAN_KEY]
+	addStage := func(name, description string) {
+		journal.Stages = append(journal.Stages, DeployStage{Name: name, Description: description, Status: DEPLOY_STAGE_PENDING})
+	}
+	// metadata first, because there may be permission set data to create
+	if mok {
+		addStage(DEPLOY_STAGE_METADATA, constants.PLINY)
+	}
+	if dok {
+		addStage(DEPLOY_STAGE_DATA, constants.WARDEN)
+		addStage(DEPLOY_STAGE_PERMISSION_SETS, "permission set sync")
+	}
+	if mok && dok {
+		addStage(DEPLOY_STAGE_SYNC, "data source sync")
+	}
+
+	return journal
+}
+
+// NewDeployJournal starts the journal of a deploy of the directory
+func NewDeployJournal(targetDir, host string, plans NlxDynamicPlanMap) *DeployJournal {
+	journal := newDeployJournal(host, plans)
+	journal.path = DeployJournalPath(targetDir)
+	return journal
+}
+
+// Batch replaces the stage deploying each of the plans with a stage for each
+// of its batches, in order
+func (journal *DeployJournal) Batch(batches map[string][]DeployBatch) {
+	stages := make([]DeployStage, 0, len(journal.Stages))
+	for _, stage := range journal.Stages {
+		planBatches := batches[stagePlanName(stage.Name)]
+		if len(planBatches) == 0 || stage.Status != DEPLOY_STAGE_PENDING {
+			stages = append(stages, stage)
+			continue
+		}
+		for i := range planBatches {
+			batch := planBatches[i]
+			stages = append(stages, DeployStage{
+				Name:        stage.Name,
+				Description: fmt.Sprintf("%v %v", stage.Description, batch),
+				Status:      DEPLOY_STAGE_PENDING,
+				Batch:       &batch,
+			})
+		}
+	}
+	journal.Stages = stages
+}
+
+// Expect records the hashes of the files that each plan's stages must
+// deploy, by plan name and then path, e.g. those of a saved plan that was
+// approved. A stage deployed in batches only expects its batch's files.
+func (journal *DeployJournal) Expect(hashes map[string]map[string]string) {
+	for i := range journal.Stages {
+		stage := &journal.Stages[i]
+		planHashes, ok := hashes[stagePlanName(stage.Name)]
+		if !ok {
+			continue
+		}
+		stage.Hashes = make(map[string]string, len(planHashes))
+		for path, hash := range planHashes {
+			if stage.Batch == nil || stage.Batch.Metadata.FilterItem(path) {
+				stage.Hashes[path] = hash
+			}
+		}
+	}
+}
+
+// stagePayload archives what a stage of the plan deploys. The first time,
+// the hashes of the archive's files are recorded in the stage; after that,
+// the archive must have the same files with the same hashes, or it's a
+// PlanChangedError.
+func stagePayload(plan NlxPlan, stage *DeployStage, targetDir string, variables util.Variables) (payload []byte, err error) {
+	if stage.Batch != nil {
+		plan.Metadata = stage.Batch.Metadata
+	}
+
+	logging.Get().Tracef("Archiving %v", targetDir)
+	if payload, err = ArchiveWithVariables(targetDir, &plan.Metadata, variables); err != nil {
+		logging.Get().Trace("Error creating deployment ZIP archive")
+		return
+	}
+
+	var hashes map[string]string
+	if hashes, err = ArchiveHashes(payload); err != nil {
+		return
+	}
+	if stage.Hashes == nil {
+		stage.Hashes = hashes
+		return
+	}
+
+	changed := make(map[string]bool)
+	added := make(map[string]bool)
+	removed := make(map[string]bool)
+	changedFiles(stage.Hashes, hashes, changed, added, removed)
+	if err = planChangedError(changed, added, removed); err != nil {
+		err = fmt.Errorf("%v won't be deployed: %w", stage.Description, err)
+	}
+	return
+}
+
+// stagePlanName returns the name of the plan that a stage deploys, if it
+// deploys one
+func stagePlanName(stageName string) string {
+	switch stageName {
+	case DEPLOY_STAGE_METADATA:
+		return METADATA_PLAN_KEY
+	case DEPLOY_STAGE_DATA:
+		return DATA_PLAN_KEY
+	}
+	return ""
+}
+
+// LoadDeployJournal reads the journal of the last deploy of the directory
+func LoadDeployJournal(targetDir string) (journal *DeployJournal, err error) {
+	path := DeployJournalPath(targetDir)
+
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("no deploy to resume in %v", targetDir)
+		}
+		return
+	}
+
+	journal = &DeployJournal{}
+	if err = json.Unmarshal(data, journal); err != nil {
+		err = fmt.Errorf("unable to read deploy journal %v: %w", path, err)
+		return
+	}
+	journal.path = path
+
+	return
+}
+
+// Save writes the journal, if it has somewhere to go
+func (journal *DeployJournal) Save() (err error) {
+	journal.Updated = time.Now().UTC()
+	if journal.path == "" {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(journal.path), 0755); err != nil {
+		return
+	}
+
+	var data []byte
+	if data, err = json.MarshalIndent(journal, "", "\t"); err != nil {
+		return
+	}
+
+	return os.WriteFile(journal.path, data, 0644)
+}
+
+// Complete returns true if every stage has been applied
+func (journal *DeployJournal) Complete() bool {
+	for _, stage := range journal.Stages {
+		if stage.Status != DEPLOY_STAGE_APPLIED {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary describes the state of each stage, e.g.
+// "Skuid NLX applied, Skuid Cloud Data Service failed, permission set sync not run"
+func (journal *DeployJournal) Summary() string {
+	descriptions := make([]string, 0, len(journal.Stages))
+	for _, stage := range journal.Stages {
+		status := stage.Status
+		if status == DEPLOY_STAGE_PENDING {
+			status = "not run"
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%v %v", stage.Description, status))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// results returns the results of the applied plan stages, one for each
+// batch of a plan deployed in batches
+func (journal *DeployJournal) results() (results []NlxDeploymentResult) {
+	results = make([]NlxDeploymentResult, 0)
+	for _, planName := range []string{METADATA_PLAN_KEY, DATA_PLAN_KEY} {
+		for _, stage := range journal.Stages {
+			if stagePlanName(stage.Name) != planName || stage.Status != DEPLOY_STAGE_APPLIED {
+				continue
+			}
+			plan := journal.Plans[planName]
+			if stage.Batch != nil {
+				plan.Metadata = stage.Batch.Metadata
+			}
+			results = append(results, NlxDeploymentResult{
+				Plan:     plan,
+				PlanName: planName,
+				Url:      stage.Url,
+				Data:     stage.Response,
+				Batch:    stage.Batch,
+			})
+		}
+	}
+	return
+}
+
+// permissionSetsFromResponse collects all app permission set UUIDs and
+// datasource permissions from pliny's response, so we can use them to create
+// datasource permissions in warden. This is necessary because we need the
+// UUIDs and the deploy plan only has the names.
+func permissionSetsFromResponse(response []byte) (permissionSets []PermissionSetResult, err error) {
+	resultMap := plinyResult{}
+	if err = json.Unmarshal(response, &resultMap); err != nil {
+		return
+	}
+	permissionSets = make([]PermissionSetResult, 0, len(resultMap.PermissionSets.Inserts)+len(resultMap.PermissionSets.Updates))
+	permissionSets = append(permissionSets, resultMap.PermissionSets.Inserts...)
+	permissionSets = append(permissionSets, resultMap.PermissionSets.Updates...)
+	return
+}
+
+// ExecuteDeployJournal runs every stage of the journal that hasn't been
+// applied, saving the journal after each one. It stops at the first stage
+// that fails. The results include stages applied by an earlier run.
+func ExecuteDeployJournal(auth *Authorization, journal *DeployJournal, targetDir string, variables util.Variables) (duration time.Duration, planResults []NlxDeploymentResult, err error) {
+	start := time.Now()
+	defer func() { duration = time.Since(start) }()
+	logging.Get().Trace("Executing Deploy Plan")
+
+	if journal.Host != auth.Host {
+		err = fmt.Errorf("deploy was planned for %v, not %v", journal.Host, auth.Host)
+		return
+	}
+
+	metaPlan, mok := journal.Plans[METADATA_PLAN_KEY]
+	dataPlan := journal.Plans[DATA_PLAN_KEY]
+
+	// Warning for metaplan
+	for _, warning := range metaPlan.Warnings {
+		logging.Get().Warnf("Warning %v", warning)
+	}
+
+	// Warning for dataplan
+	for _, warning := range dataPlan.Warnings {
+		logging.Get().Warnf("Warning %v", warning)
+	}
+
+	// archive every stage that's still to be deployed before deploying any,
+	// so that a deploy whose files change part way, or before it's resumed,
+	// stops before deploying anything it didn't start with
+	payloads := make(map[*DeployStage][]byte)
+	for i := range journal.Stages {
+		stage := &journal.Stages[i]
+		planName := stagePlanName(stage.Name)
+		if planName == "" || stage.Status == DEPLOY_STAGE_APPLIED {
+			continue
+		}
+		if payloads[stage], err = stagePayload(journal.Plans[planName], stage, targetDir, variables); err != nil {
+			return
+		}
+	}
+	if err = journal.Save(); err != nil {
+		return
+	}
+
+	deployPlan := func(plan NlxPlan, stage *DeployStage) (err error) {
+		description := plan.Type
+		if stage.Batch != nil {
+			plan.Metadata = stage.Batch.Metadata
+			description = fmt.Sprintf("%v %v", plan.Type, stage.Batch)
+		}
+		logging.Get().Infof("Deploying %v", color.Magenta.Sprint(description))
+
+		payload := payloads[stage]
+
+		headers := GeneratePlanHeaders(auth, plan)
+		logging.Get().Tracef("Plan Headers: %v\n", headers)
+
+		url := GenerateRoute(auth, plan)
+		logging.Get().Tracef("Plan Request: %v\n", url)
+
+		var response []byte
+		if response, err = Request(url, http.MethodPost, payload, headers); err != nil {
+			logging.Get().Tracef("Url: %v", url)
+			logging.Get().Tracef("Error on request: %v\n", err.Error())
+			return
+		}
+		stage.Url = url
+		stage.Response = response
+
+		logging.Get().Infof("Finished Deploying %v", color.Magenta.Sprint(description))
+		return
+	}
+
+	runStage := map[string]func(stage *DeployStage) error{
+		DEPLOY_STAGE_METADATA: func(stage *DeployStage) error {
+			return deployPlan(metaPlan, stage)
+		},
+		DEPLOY_STAGE_DATA: func(stage *DeployStage) error {
+			return deployPlan(dataPlan, stage)
+		},
+		DEPLOY_STAGE_PERMISSION_SETS: func(stage *DeployStage) (err error) {
+			if !mok {
+				return
+			}
+			// every metadata stage has been applied, but with batches the
+			// permission sets may be in any of their responses
+			dataPlan.AllPermissionSets = nil
+			for _, metadataStage := range journal.Stages {
+				if metadataStage.Name != DEPLOY_STAGE_METADATA || metadataStage.Status != DEPLOY_STAGE_APPLIED {
+					continue
+				}
+				var permissionSets []PermissionSetResult
+				if permissionSets, err = permissionSetsFromResponse(metadataStage.Response); err != nil {
+					return
+				}
+				dataPlan.AllPermissionSets = append(dataPlan.AllPermissionSets, permissionSets...)
+			}
+			if len(dataPlan.AllPermissionSets) == 0 {
+				return
+			}
+			// Create permission set datasource permissions with the UUIDs that the metadata service generated and assigned
+			return updatePermissionSets(auth, dataPlan, dataPlan.AllPermissionSets)
+		},
+		// Tell pliny to sync datasource external_id field with warden ids
+		DEPLOY_STAGE_SYNC: func(stage *DeployStage) error {
+			return syncDataSources(auth, metaPlan)
+		},
+	}
+
+	for i := range journal.Stages {
+		stage := &journal.Stages[i]
+		if stage.Status == DEPLOY_STAGE_APPLIED {
+			logging.Get().Infof("Skipping %v, already applied", color.Magenta.Sprint(stage.Description))
+			continue
+		}
+
+		run, ok := runStage[stage.Name]
+		if !ok {
+			err = fmt.Errorf("unknown deploy stage %v", stage.Name)
+			return
+		}
+
+		if err = run(stage); err != nil {
+			stage.Status = DEPLOY_STAGE_FAILED
+			stage.Error = err.Error()
+			if saveErr := journal.Save(); saveErr != nil {
+				logging.Get().Warnf("Unable to save deploy journal: %v", saveErr)
+			}
+			planResults = journal.results()
+			logging.Get().Errorf("Deploy incomplete: %v", journal.Summary())
+			err = &DeployStageError{Stage: stage.Name, Err: err}
+			return
+		}
+
+		completed := time.Now().UTC()
+		stage.Status = DEPLOY_STAGE_APPLIED
+		stage.Error = ""
+		stage.Completed = &completed
+		if err = journal.Save(); err != nil {
+			return
+		}
+	}
+
+	planResults = journal.results()
+	return
+}
@@ -0,0 +1,208 @@
+package pkg_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestNewDeployJournalStages(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		plans       pkg.NlxDynamicPlanMap
+		expected    []string
+	}{
+		{
+			description: "metadata and data",
+			plans: pkg.NlxDynamicPlanMap{
+				pkg.METADATA_PLAN_KEY: pkg.NlxPlan{},
+				pkg.DATA_PLAN_KEY:     pkg.NlxPlan{},
+			},
+			expected: []string{pkg.DEPLOY_STAGE_METADATA, pkg.DEPLOY_STAGE_DATA, pkg.DEPLOY_STAGE_PERMISSION_SETS, pkg.DEPLOY_STAGE_SYNC},
+		},
+		{
+			description: "metadata only",
+			plans:       pkg.NlxDynamicPlanMap{pkg.METADATA_PLAN_KEY: pkg.NlxPlan{}},
+			expected:    []string{pkg.DEPLOY_STAGE_METADATA},
+		},
+		{
+			description: "data only",
+			plans:       pkg.NlxDynamicPlanMap{pkg.DATA_PLAN_KEY: pkg.NlxPlan{}},
+			expected:    []string{pkg.DEPLOY_STAGE_DATA, pkg.DEPLOY_STAGE_PERMISSION_SETS},
+		},
+		{
+			description: "nothing",
+			plans:       pkg.NlxDynamicPlanMap{},
+			expected:    nil,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			journal := pkg.NewDeployJournal(t.TempDir(), "https://example.skuidsite.com", tc.plans)
+			var names []string
+			for _, stage := range journal.Stages {
+				assert.Equal(t, pkg.DEPLOY_STAGE_PENDING, stage.Status)
+				names = append(names, stage.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+			assert.Equal(t, len(tc.expected) == 0, journal.Complete())
+		})
+	}
+}
+
+func TestDeployJournalSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := pkg.LoadDeployJournal(dir)
+	assert.EqualError(t, err, "no deploy to resume in "+dir)
+
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Host: "https://example.skuidsite.com", Metadata: pkg.NlxMetadata{Pages: []string{"Home"}}},
+		pkg.DATA_PLAN_KEY:     pkg.NlxPlan{Metadata: pkg.NlxMetadata{DataSources: []string{"Orders"}}},
+	}
+	journal := pkg.NewDeployJournal(dir, "https://example.skuidsite.com", plans)
+	journal.Stages[0].Status = pkg.DEPLOY_STAGE_APPLIED
+	journal.Stages[0].Response = []byte(`{"permissionSets":{}}`)
+	journal.Stages[1].Status = pkg.DEPLOY_STAGE_FAILED
+	journal.Stages[1].Error = "500 Internal Server Error"
+	assert.NoError(t, journal.Save())
+	assert.FileExists(t, filepath.Join(dir, ".skuid", "deploy-journal.json"))
+
+	loaded, err := pkg.LoadDeployJournal(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, journal.Host, loaded.Host)
+	assert.Equal(t, journal.Plans, loaded.Plans)
+	assert.Equal(t, journal.Stages, loaded.Stages)
+	assert.False(t, loaded.Complete())
+	assert.Equal(t, "Skuid NLX applied, Skuid Cloud Data Service failed, permission set sync not run, data source sync not run", loaded.Summary())
+}
+
+func TestExecuteDeployJournal(t *testing.T) {
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Metadata: pkg.NlxMetadata{Pages: []string{"Home"}}},
+	}
+
+	// a journal for another site is never run
+	journal := pkg.NewDeployJournal(t.TempDir(), "https://other.skuidsite.com", plans)
+	_, _, err := pkg.ExecuteDeployJournal(&pkg.Authorization{Host: "https://example.skuidsite.com"}, journal, ".", nil)
+	assert.EqualError(t, err, "deploy was planned for https://other.skuidsite.com, not https://example.skuidsite.com")
+
+	// applied stages aren't run again, but their results are returned
+	journal = pkg.NewDeployJournal(t.TempDir(), "https://example.skuidsite.com", plans)
+	journal.Stages[0].Status = pkg.DEPLOY_STAGE_APPLIED
+	journal.Stages[0].Url = "https://example.skuidsite.com/api/v2/metadata/deploy"
+	journal.Stages[0].Response = []byte(`{"pages":{"updates":["Home"]}}`)
+	_, results, err := pkg.ExecuteDeployJournal(&pkg.Authorization{Host: "https://example.skuidsite.com"}, journal, ".", nil)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, pkg.METADATA_PLAN_KEY, results[0].PlanName)
+		assert.Equal(t, journal.Stages[0].Url, results[0].Url)
+		assert.Equal(t, journal.Stages[0].Response, results[0].Data)
+	}
+}
+
+func TestExecuteDeployJournalChangedFiles(t *testing.T) {
+	dir := writeTestSite(t, map[string]string{
+		"pages/Home.json": `{"name":"Home"}`,
+		"pages/Home.xml":  `<skuidpage/>`,
+	})
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Type: pkg.METADATA_PLAN_TYPE, Endpoint: "/metadata/deploy", Metadata: pkg.NlxMetadata{Pages: []string{"Home"}}},
+	}
+
+	requests := 0
+	auth := testSite(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	// the first run records what it archived, even though it fails
+	journal := pkg.NewDeployJournal(dir, auth.Host, plans)
+	_, _, err := pkg.ExecuteDeployJournal(auth, journal, dir, nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+
+	journal, err = pkg.LoadDeployJournal(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.DEPLOY_STAGE_FAILED, journal.Stages[0].Status)
+	assert.Len(t, journal.Stages[0].Hashes, 2)
+
+	// resuming after the files changed deploys nothing
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "pages", "Home.xml"), []byte(`<skuidpage title="changed"/>`), 0644))
+	_, _, err = pkg.ExecuteDeployJournal(auth, journal, dir, nil)
+	assert.EqualError(t, err, "Skuid NLX won't be deployed: local files have changed since the plan was made: changed pages/Home.xml")
+	assert.ErrorAs(t, err, &pkg.PlanChangedError{})
+	assert.Equal(t, 1, requests)
+
+	// nor does a journal that expects other files, e.g. from a saved plan
+	journal = pkg.NewDeployJournal(dir, auth.Host, plans)
+	journal.Expect(map[string]map[string]string{
+		pkg.METADATA_PLAN_KEY: {"pages/Home.json": "0000"},
+	})
+	_, _, err = pkg.ExecuteDeployJournal(auth, journal, dir, nil)
+	assert.EqualError(t, err, "Skuid NLX won't be deployed: local files have changed since the plan was made: changed pages/Home.json; added pages/Home.xml")
+	assert.Equal(t, 1, requests)
+}
+
+func TestDeployJournalExpectBatches(t *testing.T) {
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Metadata: pkg.NlxMetadata{Pages: []string{"Home", "Orders"}}},
+	}
+	journal := pkg.NewDeployJournal(t.TempDir(), "https://example.skuidsite.com", plans)
+	journal.Batch(map[string][]pkg.DeployBatch{
+		pkg.METADATA_PLAN_KEY: {
+			{Number: 1, Of: 2, Metadata: pkg.NlxMetadata{Pages: []string{"Home"}}},
+			{Number: 2, Of: 2, Metadata: pkg.NlxMetadata{Pages: []string{"Orders"}}},
+		},
+	})
+	journal.Expect(map[string]map[string]string{
+		pkg.METADATA_PLAN_KEY: {"pages/Home.json": "a", "pages/Home.xml": "b", "pages/Orders.json": "c"},
+	})
+
+	// each batch only expects its own files
+	assert.Equal(t, map[string]string{"pages/Home.json": "a", "pages/Home.xml": "b"}, journal.Stages[0].Hashes)
+	assert.Equal(t, map[string]string{"pages/Orders.json": "c"}, journal.Stages[1].Hashes)
+}
+
+func TestDeployJournalBatch(t *testing.T) {
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Metadata: pkg.NlxMetadata{Pages: []string{"Home", "Orders"}}},
+		pkg.DATA_PLAN_KEY:     pkg.NlxPlan{Metadata: pkg.NlxMetadata{DataSources: []string{"Db"}}},
+	}
+	journal := pkg.NewDeployJournal(t.TempDir(), "https://example.skuidsite.com", plans)
+	journal.Batch(map[string][]pkg.DeployBatch{
+		pkg.METADATA_PLAN_KEY: {
+			{Number: 1, Of: 2, Metadata: pkg.NlxMetadata{Pages: []string{"Home"}}},
+			{Number: 2, Of: 2, Metadata: pkg.NlxMetadata{Pages: []string{"Orders"}}},
+		},
+	})
+
+	var names []string
+	for _, stage := range journal.Stages {
+		names = append(names, stage.Name)
+	}
+	assert.Equal(t, []string{pkg.DEPLOY_STAGE_METADATA, pkg.DEPLOY_STAGE_METADATA, pkg.DEPLOY_STAGE_DATA, pkg.DEPLOY_STAGE_PERMISSION_SETS, pkg.DEPLOY_STAGE_SYNC}, names)
+	assert.Equal(t, []string{"Home"}, journal.Stages[0].Batch.Metadata.Pages)
+	assert.Nil(t, journal.Stages[2].Batch)
+
+	journal.Stages[0].Status = pkg.DEPLOY_STAGE_APPLIED
+	journal.Stages[0].Response = []byte(`{"pages":{"updates":["Home"]}}`)
+	journal.Stages[1].Status = pkg.DEPLOY_STAGE_FAILED
+	assert.Equal(t, "Skuid NLX batch 1 of 2 applied, Skuid NLX batch 2 of 2 failed, Skuid Cloud Data Service not run, permission set sync not run, data source sync not run", journal.Summary())
+
+	// applied batches are resumed from, with their own metadata in the results
+	journal.Stages[1].Status = pkg.DEPLOY_STAGE_APPLIED
+	journal.Stages[1].Response = []byte(`{"pages":{"inserts":["Orders"]}}`)
+	journal.Stages = journal.Stages[:2]
+	_, results, err := pkg.ExecuteDeployJournal(&pkg.Authorization{Host: "https://example.skuidsite.com"}, journal, ".", nil)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, []string{"Home"}, results[0].Plan.Metadata.Pages)
+		assert.Equal(t, 1, results[0].Batch.Number)
+		assert.Equal(t, []string{"Orders"}, results[1].Plan.Metadata.Pages)
+	}
+}
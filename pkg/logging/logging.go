@@ -62,6 +62,17 @@ func SetDiagnostic() logrus.Ext1FieldLogger {
 	return loggerSingleton
 }
 
+// SetStderr sends the log to stderr instead of stdout, so that it doesn't
+// mix with output that has to be parsed. A log file is left alone.
+func SetStderr() logrus.Ext1FieldLogger {
+	loggerSingleton = Get()
+	if !fileLogging {
+		l, _ := loggerSingleton.(*logrus.Logger)
+		l.SetOutput(os.Stderr)
+	}
+	return loggerSingleton
+}
+
 func SetFieldLogging(b bool) {
 	fieldLogging = b
 }
@@ -107,20 +118,23 @@ func SetFileLogging(loggingDirectory string) (err error) {
 	return
 }
 
+// WithFields returns a logger that includes the fields, when fields are being
+// logged. The shared logger is left alone, so this is safe to call from
+// concurrent deploys.
 func WithFields(fields logrus.Fields) logrus.Ext1FieldLogger {
-	loggerSingleton = Get()
-	if fileLogging || Level(loggerSingleton) == logrus.TraceLevel && fieldLogging {
-		loggerSingleton = loggerSingleton.WithFields(fields)
+	logger := Get()
+	if fileLogging || Level(logger) == logrus.TraceLevel && fieldLogging {
+		return logger.WithFields(fields)
 	}
-	return loggerSingleton
+	return logger
 }
 
 func WithField(field string, value interface{}) logrus.Ext1FieldLogger {
-	loggerSingleton = Get()
-	if fileLogging || Level(loggerSingleton) == logrus.TraceLevel && fieldLogging {
-		loggerSingleton = loggerSingleton.WithField(field, value)
+	logger := Get()
+	if fileLogging || Level(logger) == logrus.TraceLevel && fieldLogging {
+		return logger.WithField(field, value)
 	}
-	return loggerSingleton
+	return logger
 }
 
 func Reset() logrus.Ext1FieldLogger {
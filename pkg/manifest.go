@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+const (
+	// DEPLOY_MANIFESTS_DIRECTORY is relative to the deployed directory, with
+	// a manifest for each host
+	DEPLOY_MANIFESTS_DIRECTORY = ".skuid/manifests"
+)
+
+var manifestFileNameUnsafe = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// DeployManifest records a hash of the content of each entity as it was last
+// successfully deployed to a host, so that unchanged entities can be skipped
+type DeployManifest struct {
+	Host    string    `json:"host"`
+	Updated time.Time `json:"updated"`
+	// Entities are content hashes by metadata type and name, e.g. pages/Home
+	Entities map[string]string `json:"entities"`
+
+	path string
+}
+
+// DeployManifestPath returns where the manifest for deploys of a directory to
+// a host is kept
+func DeployManifestPath(targetDir, host string) string {
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://"), "/"))
+	name = manifestFileNameUnsafe.ReplaceAllString(name, "_")
+	return filepath.Join(targetDir, filepath.FromSlash(DEPLOY_MANIFESTS_DIRECTORY), name+".json")
+}
+
+// LoadDeployManifest reads the manifest of deploys of the directory to the
+// host. A host that hasn't been deployed to has an empty manifest.
+func LoadDeployManifest(targetDir, host string) (manifest *DeployManifest, err error) {
+	path := DeployManifestPath(targetDir, host)
+	manifest = &DeployManifest{
+		Host:     host,
+		Entities: make(map[string]string),
+		path:     path,
+	}
+
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	if err = json.Unmarshal(data, manifest); err != nil {
+		err = fmt.Errorf("unable to read deploy manifest %v: %w", path, err)
+		return
+	}
+	if manifest.Entities == nil {
+		manifest.Entities = make(map[string]string)
+	}
+	return
+}
+
+// Save writes the manifest
+func (manifest *DeployManifest) Save() (err error) {
+	manifest.Updated = time.Now().UTC()
+
+	if err = os.MkdirAll(filepath.Dir(manifest.path), 0755); err != nil {
+		return
+	}
+
+	var data []byte
+	if data, err = json.MarshalIndent(manifest, "", "\t"); err != nil {
+		return
+	}
+
+	return os.WriteFile(manifest.path, data, 0644)
+}
+
+// Changed returns the entities whose hash isn't the one last deployed
+func (manifest *DeployManifest) Changed(hashes map[string]string) (changed NlxMetadata) {
+	keys := make([]string, 0, len(hashes))
+	for key := range hashes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if manifest.Entities[key] != hashes[key] {
+			metadataType, name, _ := strings.Cut(key, "/")
+			changed.Add(metadataType, name)
+		}
+	}
+	return
+}
+
+// Update records the hashes of the deployed entities
+func (manifest *DeployManifest) Update(hashes map[string]string, deployed NlxMetadata) {
+	for _, metadataType := range GetMetadataTypeDirNames() {
+		names, _ := deployed.GetFieldValueByName(metadataType)
+		for _, name := range names {
+			key := entityKey(metadataType, name)
+			if hash, ok := hashes[key]; ok {
+				manifest.Entities[key] = hash
+			}
+		}
+	}
+}
+
+// EntityHashes returns a hash of each entity's files in the directory, as
+// they would be deployed with the variables, by metadata type and name
+func EntityHashes(targetDir string, variables util.Variables) (hashes map[string]string, err error) {
+	var payload []byte
+	if payload, err = ArchiveWithVariables(targetDir, nil, variables); err != nil {
+		return
+	}
+
+	var fileHashes map[string]string
+	if fileHashes, err = ArchiveHashes(payload); err != nil {
+		return
+	}
+
+	paths := make([]string, 0, len(fileHashes))
+	for path := range fileHashes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// an entity's hash covers the names and contents of all of its files
+	entityFiles := make(map[string][]string)
+	for _, path := range paths {
+		metadataType, name, ok := EntityFromPath(path)
+		if !ok {
+			continue
+		}
+		key := entityKey(metadataType, name)
+		entityFiles[key] = append(entityFiles[key], fmt.Sprintf("%v  %v\n", fileHashes[path], path))
+	}
+
+	hashes = make(map[string]string, len(entityFiles))
+	for key, lines := range entityFiles {
+		hash := sha256.New()
+		for _, line := range lines {
+			hash.Write([]byte(line))
+		}
+		hashes[key] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return
+}
+
+// UpdateDeployManifest records the entities in the plans as deployed to the
+// host, with the hashes from EntityHashes taken before the deploy, since the
+// directory may have changed while it ran
+func UpdateDeployManifest(targetDir, host string, plans NlxDynamicPlanMap, hashes map[string]string) (err error) {
+	var manifest *DeployManifest
+	if manifest, err = LoadDeployManifest(targetDir, host); err != nil {
+		return
+	}
+
+	for _, name := range SortedPlanNames(plans) {
+		manifest.Update(hashes, plans[name].Metadata)
+	}
+	return manifest.Save()
+}
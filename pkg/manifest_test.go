@@ -0,0 +1,85 @@
+package pkg_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestDeployManifestPath(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		host        string
+		expected    string
+	}{
+		{
+			description: "host",
+			host:        "my-site.skuidsite.com",
+			expected:    "my-site.skuidsite.com.json",
+		},
+		{
+			description: "scheme, case and trailing slash",
+			host:        "https://My-Site.skuidsite.com/",
+			expected:    "my-site.skuidsite.com.json",
+		},
+		{
+			description: "port",
+			host:        "http://localhost:3000",
+			expected:    "localhost_3000.json",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, filepath.Join("site", ".skuid", "manifests", tc.expected), pkg.DeployManifestPath("site", tc.host))
+		})
+	}
+}
+
+func TestDeployManifest(t *testing.T) {
+	dir := t.TempDir()
+	host := "my-site.skuidsite.com"
+	write := func(path, content string) {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write("pages/Home.json", `{"name":"Home"}`)
+	write("pages/Home.xml", `<skuidpage/>`)
+	write("pages/Orders.json", `{"name":"Orders"}`)
+	write("pages/Orders.xml", `<skuidpage/>`)
+	write("apps/Sales.json", `{"name":"Sales"}`)
+
+	hashes, err := pkg.EntityHashes(dir, nil)
+	assert.NoError(t, err)
+	assert.Len(t, hashes, 3)
+
+	// a site that hasn't been deployed to has changed everything
+	manifest, err := pkg.LoadDeployManifest(dir, host)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.NlxMetadata{Apps: []string{"Sales"}, Pages: []string{"Home", "Orders"}}, manifest.Changed(hashes))
+
+	// only the deployed entities are recorded, as they were before the
+	// deploy, not as they were edited while it ran
+	write("pages/Orders.xml", `<skuidpage><models/></skuidpage>`)
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Metadata: pkg.NlxMetadata{Pages: []string{"Home", "Orders"}}},
+	}
+	assert.NoError(t, pkg.UpdateDeployManifest(dir, host, plans, hashes))
+	manifest, err = pkg.LoadDeployManifest(dir, host)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.NlxMetadata{Apps: []string{"Sales"}}, manifest.Changed(hashes))
+
+	// changing any of an entity's files changes it
+	write("pages/Home.xml", `<skuidpage><models/></skuidpage>`)
+	hashes, err = pkg.EntityHashes(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.NlxMetadata{Apps: []string{"Sales"}, Pages: []string{"Home", "Orders"}}, manifest.Changed(hashes))
+
+	// each host has its own manifest
+	other, err := pkg.LoadDeployManifest(dir, "other.skuidsite.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, other.Changed(hashes).Count())
+}
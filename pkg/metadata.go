@@ -163,3 +163,138 @@ func GetMetadataTypeDirNames() (types []string) {
 
 	return types
 }
+
+// EntityFromPath returns the metadata type and entity name that a file
+// (relative to the metadata directory) belongs to, following the same naming
+// rules as FilterItem. ok is false for files outside of a metadata type directory.
+func EntityFromPath(item string) (metadataType string, name string, ok bool) {
+	cleanRelativeFilePath := filepath.ToSlash(util.FromWindowsPath(item))
+	parts := strings.Split(strings.TrimPrefix(cleanRelativeFilePath, "./"), "/")
+	if len(parts) < 2 || !util.StringSliceContainsKey(GetMetadataTypeDirNames(), parts[0]) {
+		return
+	}
+
+	metadataType = parts[0]
+	filePath := strings.Join(parts[1:], "/")
+	ok = true
+
+	switch metadataType {
+	case "componentpacks":
+		// everything in a component pack folder belongs to the pack
+		name = parts[1]
+	case "tables", "workflows", "documents":
+		name = strings.SplitN(filePath, ".", 2)[0]
+	case "connectionvariables":
+		// connection variables are named datasourcename-variablename
+		name = strings.TrimSuffix(filePath, ".json")
+		if nameParts := strings.Split(name, "-"); len(nameParts) >= 2 {
+			name = nameParts[1]
+		}
+	default:
+		name = filePath
+		for _, suffix := range []string{".skuid.json", ".inline.css", ".json", ".xml"} {
+			if strings.HasSuffix(name, suffix) {
+				name = strings.TrimSuffix(name, suffix)
+				break
+			}
+		}
+	}
+
+	return
+}
+
+// combineMetadata applies combine to the names of each metadata type
+func combineMetadata(a, b NlxMetadata, combine func(a, b []string) []string) (result NlxMetadata) {
+	aValue, bValue := reflect.ValueOf(a), reflect.ValueOf(b)
+	resultValue := reflect.ValueOf(&result).Elem()
+	for i := 0; i < resultValue.NumField(); i++ {
+		if names := combine(aValue.Field(i).Interface().([]string), bValue.Field(i).Interface().([]string)); len(names) > 0 {
+			resultValue.Field(i).Set(reflect.ValueOf(names))
+		}
+	}
+	return
+}
+
+// Union returns the entities in either metadata
+func (from NlxMetadata) Union(other NlxMetadata) NlxMetadata {
+	return combineMetadata(from, other, func(a, b []string) (names []string) {
+		for _, name := range append(append([]string{}, a...), b...) {
+			if !util.StringSliceContainsKey(names, name) {
+				names = append(names, name)
+			}
+		}
+		return
+	})
+}
+
+// Intersect returns the entities in both metadata
+func (from NlxMetadata) Intersect(other NlxMetadata) NlxMetadata {
+	return combineMetadata(from, other, func(a, b []string) (names []string) {
+		for _, name := range a {
+			if util.StringSliceContainsKey(b, name) && !util.StringSliceContainsKey(names, name) {
+				names = append(names, name)
+			}
+		}
+		return
+	})
+}
+
+// Difference returns the entities that are not in the other metadata
+func (from NlxMetadata) Difference(other NlxMetadata) NlxMetadata {
+	return combineMetadata(from, other, func(a, b []string) (names []string) {
+		for _, name := range a {
+			if !util.StringSliceContainsKey(b, name) && !util.StringSliceContainsKey(names, name) {
+				names = append(names, name)
+			}
+		}
+		return
+	})
+}
+
+// Count returns the number of entities across every metadata type
+func (from NlxMetadata) Count() (count int) {
+	value := reflect.ValueOf(from)
+	for i := 0; i < value.NumField(); i++ {
+		count += value.Field(i).Len()
+	}
+	return
+}
+
+// Add adds an entity of a metadata type, by its directory name, if it isn't
+// there already. Unknown metadata types are ignored.
+func (from *NlxMetadata) Add(metadataType, name string) {
+	value := reflect.ValueOf(from).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if value.Type().Field(i).Tag.Get("json") != metadataType {
+			continue
+		}
+		field := value.Field(i)
+		if names := field.Interface().([]string); !util.StringSliceContainsKey(names, name) {
+			field.Set(reflect.ValueOf(append(names, name)))
+		}
+		return
+	}
+}
+
+// OnlyTypes returns the entities of the metadata types, by their directory
+// names
+func (from NlxMetadata) OnlyTypes(types []string) (metadata NlxMetadata) {
+	for _, metadataType := range types {
+		names, _ := from.GetFieldValueByName(metadataType)
+		for _, name := range names {
+			metadata.Add(metadataType, name)
+		}
+	}
+	return
+}
+
+// MetadataFromPaths returns the entities that the files (relative to the
+// metadata directory) belong to
+func MetadataFromPaths(paths []string) (metadata NlxMetadata) {
+	for _, path := range paths {
+		if metadataType, name, ok := EntityFromPath(path); ok {
+			metadata.Add(metadataType, name)
+		}
+	}
+	return
+}
@@ -191,3 +191,65 @@ func TestGetMetadataByString(t *testing.T) {
 		})
 	}
 }
+
+func TestEntityFromPath(t *testing.T) {
+	for _, tc := range []struct {
+		given        string
+		expectedType string
+		expectedName string
+		expectedOk   bool
+	}{
+		{"pages/Home.xml", "pages", "Home", true},
+		{"pages/Home.json", "pages", "Home", true},
+		{"pages/Home.skuid.json", "pages", "Home", true},
+		{`pages\Home.xml`, "pages", "Home", true},
+		{"themes/Dark.inline.css", "themes", "Dark", true},
+		{"componentpacks/charts/js/runtime.js", "componentpacks", "charts", true},
+		{"connectionvariables/Orders-apikey.json", "connectionvariables", "apikey", true},
+		{"tables/orders.sql", "tables", "orders", true},
+		{"workflows/approve.json", "workflows", "approve", true},
+		{"files/logo.png", "files", "logo.png", true},
+		{"files/logo.png.skuid.json", "files", "logo.png", true},
+		{"site/site.json", "site", "site", true},
+		{"README.md", "", "", false},
+		{"unknown/thing.json", "", "", false},
+	} {
+		t.Run(tc.given, func(t *testing.T) {
+			metadataType, name, ok := pkg.EntityFromPath(tc.given)
+			assert.Equal(t, tc.expectedOk, ok)
+			assert.Equal(t, tc.expectedType, metadataType)
+			assert.Equal(t, tc.expectedName, name)
+		})
+	}
+}
+
+func TestMetadataSetOperations(t *testing.T) {
+	a := pkg.NlxMetadata{Pages: []string{"Home", "Old"}, Apps: []string{"Sales"}}
+	b := pkg.NlxMetadata{Pages: []string{"Home", "New"}, Themes: []string{"Dark"}}
+
+	assert.Equal(t, pkg.NlxMetadata{
+		Pages:  []string{"Home", "Old", "New"},
+		Apps:   []string{"Sales"},
+		Themes: []string{"Dark"},
+	}, a.Union(b))
+	assert.Equal(t, pkg.NlxMetadata{Pages: []string{"Home"}}, a.Intersect(b))
+	assert.Equal(t, pkg.NlxMetadata{Pages: []string{"Old"}, Apps: []string{"Sales"}}, a.Difference(b))
+	assert.Equal(t, 3, a.Count())
+	assert.Equal(t, 0, pkg.NlxMetadata{}.Count())
+}
+
+func TestMetadataFromPaths(t *testing.T) {
+	assert.Equal(t, pkg.NlxMetadata{
+		Pages:          []string{"Home"},
+		ComponentPacks: []string{"charts"},
+		Themes:         []string{"Dark"},
+	}, pkg.MetadataFromPaths([]string{
+		"pages/Home.json",
+		"pages/Home.xml",
+		"componentpacks/charts/manifest.json",
+		"componentpacks/charts/runtime.js",
+		"themes/Dark.json",
+		"themes/Dark.inline.css",
+		"README.md",
+	}))
+}
@@ -0,0 +1,99 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gookit/color"
+
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+const (
+	PAGES_DIRECTORY = "pages"
+)
+
+// pageModuleMetadata is the portion of a page's .json metadata file
+// that we care about for module filtering
+type pageModuleMetadata struct {
+	Name   string `json:"name"`
+	Module string `json:"module"`
+}
+
+// PageMatchesModules returns true if a page with the given module belongs
+// to one of the requested modules, or if it has no module and noModule is set
+func PageMatchesModules(pageModule string, modules []string, noModule bool) bool {
+	if pageModule == "" {
+		return noModule
+	}
+	for _, module := range modules {
+		if strings.EqualFold(module, pageModule) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetModulePageNames reads the page metadata in the target directory and
+// returns the names of the pages that belong to one of the requested modules
+// (or that have no module at all when noModule is set)
+func GetModulePageNames(targetDir string, modules []string, noModule bool) (pageNames []string, err error) {
+	pagesDir := filepath.Join(targetDir, PAGES_DIRECTORY)
+
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(pagesDir); err != nil {
+		if os.IsNotExist(err) {
+			logging.Get().Debugf("No pages directory found at %v", color.Cyan.Sprint(pagesDir))
+			err = nil
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		// page metadata lives in <name>.json (or <name>.skuid.json) next to <name>.xml
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		var data []byte
+		if data, err = os.ReadFile(filepath.Join(pagesDir, entry.Name())); err != nil {
+			return
+		}
+
+		// a page we can't read might be in the module, so don't guess
+		var page pageModuleMetadata
+		if err = json.Unmarshal(data, &page); err != nil {
+			err = fmt.Errorf("unable to read page metadata %v: %w", filepath.Join(pagesDir, entry.Name()), err)
+			return
+		}
+
+		if page.Name == "" {
+			page.Name = strings.TrimSuffix(strings.TrimSuffix(entry.Name(), ".json"), ".skuid")
+		}
+
+		if PageMatchesModules(page.Module, modules, noModule) && !util.StringSliceContainsKey(pageNames, page.Name) {
+			logging.Get().Tracef("Page %v matches module filter", color.Green.Sprint(page.Name))
+			pageNames = append(pageNames, page.Name)
+		}
+	}
+
+	return
+}
+
+// FilterPageNamesByModule narrows the requested page names down to those in the
+// module page names. If no page names were requested, all module pages are used.
+func FilterPageNamesByModule(requested []string, modulePages []string) (pageNames []string) {
+	if len(requested) == 0 {
+		return modulePages
+	}
+	for _, page := range requested {
+		if util.StringSliceContainsKey(modulePages, page) {
+			pageNames = append(pageNames, page)
+		}
+	}
+	return
+}
@@ -0,0 +1,121 @@
+package pkg_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestGetModulePageNames(t *testing.T) {
+	dir := t.TempDir()
+	pagesDir := filepath.Join(dir, "pages")
+	if err := os.MkdirAll(pagesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, body := range map[string]string{
+		"SalesHome.json":    `{"name":"SalesHome","module":"Sales"}`,
+		"SalesHome.xml":     `<skuid__page/>`,
+		"SalesDetail.json":  `{"name":"SalesDetail","module":"sales"}`,
+		"SupportHome.json":  `{"name":"SupportHome","module":"Support"}`,
+		"Orphan.json":       `{"name":"Orphan"}`,
+		"EmptyModule.json":  `{"name":"EmptyModule","module":""}`,
+		"Unnamed.json":      `{"module":"Support"}`,
+		"Unnamed.xml":       `<skuid__page/>`,
+		"Legacy.skuid.json": `{"module":"Sales"}`,
+	} {
+		if err := os.WriteFile(filepath.Join(pagesDir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		description   string
+		givenModules  []string
+		givenNoModule bool
+		expected      []string
+	}{
+		{
+			description:  "single module, case insensitive",
+			givenModules: []string{"Sales"},
+			expected:     []string{"Legacy", "SalesDetail", "SalesHome"},
+		},
+		{
+			description:  "multiple modules, name falls back to file name",
+			givenModules: []string{"Sales", "Support"},
+			expected:     []string{"Legacy", "SalesDetail", "SalesHome", "SupportHome", "Unnamed"},
+		},
+		{
+			description:   "no module",
+			givenNoModule: true,
+			expected:      []string{"EmptyModule", "Orphan"},
+		},
+		{
+			description:   "module and no module",
+			givenModules:  []string{"Support"},
+			givenNoModule: true,
+			expected:      []string{"EmptyModule", "Orphan", "SupportHome", "Unnamed"},
+		},
+		{
+			description:  "unknown module",
+			givenModules: []string{"Marketing"},
+			expected:     nil,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			actual, err := pkg.GetModulePageNames(dir, tc.givenModules, tc.givenNoModule)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestGetModulePageNamesNoPages(t *testing.T) {
+	actual, err := pkg.GetModulePageNames(t.TempDir(), []string{"Sales"}, false)
+	assert.NoError(t, err)
+	assert.Empty(t, actual)
+}
+
+func TestGetModulePageNamesMalformed(t *testing.T) {
+	dir := writeTestSite(t, map[string]string{
+		"pages/SalesHome.json": `{"name":"SalesHome","module":"Sales"}`,
+		"pages/Broken.json":    `{"name":"Broken",`,
+	})
+	_, err := pkg.GetModulePageNames(dir, []string{"Sales"}, false)
+	assert.EqualError(t, err, "unable to read page metadata "+filepath.Join(dir, "pages", "Broken.json")+": unexpected end of JSON input")
+}
+
+func TestFilterPageNamesByModule(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		requested   []string
+		modulePages []string
+		expected    []string
+	}{
+		{
+			description: "no requested pages",
+			modulePages: []string{"A", "B"},
+			expected:    []string{"A", "B"},
+		},
+		{
+			description: "intersection",
+			requested:   []string{"A", "C"},
+			modulePages: []string{"A", "B"},
+			expected:    []string{"A"},
+		},
+		{
+			description: "no overlap",
+			requested:   []string{"C"},
+			modulePages: []string{"A", "B"},
+			expected:    nil,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, pkg.FilterPageNamesByModule(tc.requested, tc.modulePages))
+		})
+	}
+}
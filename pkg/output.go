@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/skuid/skuid-cli/pkg/errors"
+)
+
+const (
+	OUTPUT_FORMAT_TABLE = "table"
+	OUTPUT_FORMAT_JSON  = "json"
+	OUTPUT_FORMAT_YAML  = "yaml"
+)
+
+var (
+	OutputFormats = []string{
+		OUTPUT_FORMAT_TABLE, OUTPUT_FORMAT_JSON, OUTPUT_FORMAT_YAML,
+	}
+)
+
+// ValidateOutputFormat returns the normalized output format, or an error
+// if the format isn't one we know how to write
+func ValidateOutputFormat(format string) (normalized string, err error) {
+	normalized = strings.ToLower(strings.TrimSpace(format))
+	if normalized == "" {
+		normalized = OUTPUT_FORMAT_TABLE
+	}
+	for _, valid := range OutputFormats {
+		if normalized == valid {
+			return
+		}
+	}
+	err = errors.Error("unknown output format '%v', expected one of: %v", format, strings.Join(OutputFormats, ", "))
+	return
+}
+
+// WriteStructured writes the value as indented json or yaml. The yaml is
+// produced from the json representation so that the json struct tags (and
+// their ordering) are honored for both formats.
+func WriteStructured(w io.Writer, format string, value interface{}) (err error) {
+	var data []byte
+	if data, err = json.MarshalIndent(value, "", "\t"); err != nil {
+		return
+	}
+
+	switch format {
+	case OUTPUT_FORMAT_JSON:
+		data = append(data, '\n')
+	case OUTPUT_FORMAT_YAML:
+		var node yaml.Node
+		if err = yaml.Unmarshal(data, &node); err != nil {
+			return
+		}
+		resetYamlStyle(&node)
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(&node); err != nil {
+			return
+		}
+		if err = encoder.Close(); err != nil {
+			return
+		}
+		data = buffer.Bytes()
+	default:
+		err = errors.Error("unable to write structured output as '%v'", format)
+		return
+	}
+
+	_, err = w.Write(data)
+	return
+}
+
+// resetYamlStyle clears the flow style that yaml picks up from json so
+// that the output is written as block yaml
+func resetYamlStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYamlStyle(child)
+	}
+}
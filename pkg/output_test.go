@@ -0,0 +1,54 @@
+package pkg_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestValidateOutputFormat(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		given       string
+		expected    string
+		expectErr   bool
+	}{
+		{description: "default", given: "", expected: pkg.OUTPUT_FORMAT_TABLE},
+		{description: "json", given: "json", expected: pkg.OUTPUT_FORMAT_JSON},
+		{description: "mixed case yaml", given: " YAML ", expected: pkg.OUTPUT_FORMAT_YAML},
+		{description: "unknown", given: "xml", expectErr: true},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			actual, err := pkg.ValidateOutputFormat(tc.given)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestWriteStructured(t *testing.T) {
+	value := pkg.NlxPlan{
+		Since: "now",
+		Metadata: pkg.NlxMetadata{
+			Pages: []string{"Home"},
+		},
+	}
+
+	var jsonOut bytes.Buffer
+	assert.NoError(t, pkg.WriteStructured(&jsonOut, pkg.OUTPUT_FORMAT_JSON, value))
+	assert.Contains(t, jsonOut.String(), `"since": "now"`)
+
+	var yamlOut bytes.Buffer
+	assert.NoError(t, pkg.WriteStructured(&yamlOut, pkg.OUTPUT_FORMAT_YAML, value))
+	assert.Contains(t, yamlOut.String(), "since: now\n")
+	assert.Contains(t, yamlOut.String(), "  pages:\n    - Home\n")
+
+	assert.Error(t, pkg.WriteStructured(&bytes.Buffer{}, pkg.OUTPUT_FORMAT_TABLE, value))
+}
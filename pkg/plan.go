@@ -3,6 +3,9 @@ package pkg
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
 	"time"
 )
 
@@ -34,11 +37,23 @@ func (t JSONTime) MarshalJSON() ([]byte, error) {
 	return []byte(tstr), nil
 }
 
+// Metadata returns the entities of every service in the plan
+func (plans NlxPlanPayload) Metadata() (metadata NlxMetadata) {
+	for _, plan := range []*NlxPlan{plans.MetadataService, plans.CloudDataService} {
+		if plan != nil {
+			metadata = metadata.Union(plan.Metadata)
+		}
+	}
+	return
+}
+
 // NlxPlanFilter should be serialized and provided with the
 // request for retrieval
 type NlxPlanFilter struct {
 	AppName                  string    `json:"appName,omitempty"`
 	PageNames                []string  `json:"pages,omitempty"`
+	Modules                  []string  `json:"modules,omitempty"`
+	NoModule                 bool      `json:"noModule,omitempty"`
 	IgnoreSkuidDb            bool      `json:"ignoreSkuidDb,omitempty"`
 	IgnoreCompatibilityCheck bool      `json:"ignoreCompatibilityCheck,omitempty"`
 	Since                    time.Time `json:"since,omitempty"`
@@ -59,3 +74,51 @@ func NewRetrievalRequestBody(metadata NlxMetadata, since string, appSpecific boo
 	})
 	return
 }
+
+// WritePlanTable writes a human readable summary of a retrieval plan
+// with a row for each metadata type that has entities in the plan
+func WritePlanTable(w io.Writer, plans NlxPlanPayload) (err error) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for _, service := range []struct {
+		name string
+		plan *NlxPlan
+	}{
+		{METADATA_PLAN_KEY, plans.MetadataService},
+		{DATA_PLAN_KEY, plans.CloudDataService},
+	} {
+		if service.plan == nil {
+			continue
+		}
+
+		fmt.Fprintf(tw, "%v\tsince: %v\tappSpecific: %v\n", service.name, service.plan.Since, service.plan.AppSpecific)
+		if err = writeMetadataRows(tw, service.plan.Metadata); err != nil {
+			return
+		}
+		fmt.Fprintln(tw)
+	}
+
+	return tw.Flush()
+}
+
+// writeMetadataRows writes the type, count and names of each metadata
+// type with entities, followed by the total
+func writeMetadataRows(tw *tabwriter.Writer, metadata NlxMetadata) (err error) {
+	fmt.Fprintln(tw, "TYPE\tCOUNT\tNAMES")
+
+	total := 0
+	for _, metadataType := range GetMetadataTypeDirNames() {
+		var names []string
+		if names, err = metadata.GetFieldValueByName(metadataType); err != nil {
+			return
+		}
+		if len(names) == 0 {
+			continue
+		}
+		total += len(names)
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", metadataType, len(names), strings.Join(names, ", "))
+	}
+	fmt.Fprintf(tw, "total\t%v\t\n", total)
+
+	return
+}
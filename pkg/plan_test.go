@@ -1 +1,51 @@
 package pkg_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestWritePlanTable(t *testing.T) {
+	plans := pkg.NlxPlanPayload{
+		MetadataService: &pkg.NlxPlan{
+			Since: "2023-01-02T03:04:05Z",
+			Metadata: pkg.NlxMetadata{
+				Apps:  []string{"Sales"},
+				Pages: []string{"Home", "Detail"},
+			},
+		},
+		CloudDataService: &pkg.NlxPlan{
+			AppSpecific: true,
+			Metadata: pkg.NlxMetadata{
+				DataSources: []string{"Orders"},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	err := pkg.WritePlanTable(&out, plans)
+	assert.NoError(t, err)
+
+	actual := out.String()
+	assert.Contains(t, actual, "skuidMetadataService  since: 2023-01-02T03:04:05Z")
+	assert.Contains(t, actual, "skuidCloudDataService  since:")
+	assert.Contains(t, actual, "appSpecific: true")
+	assert.Regexp(t, `pages\s+2\s+Home, Detail`, actual)
+	assert.Regexp(t, `apps\s+1\s+Sales`, actual)
+	assert.Regexp(t, `datasources\s+1\s+Orders`, actual)
+	assert.NotContains(t, actual, "themes")
+}
+
+func TestWritePlanTableMissingService(t *testing.T) {
+	var out bytes.Buffer
+	err := pkg.WritePlanTable(&out, pkg.NlxPlanPayload{
+		MetadataService: &pkg.NlxPlan{},
+	})
+	assert.NoError(t, err)
+	assert.NotContains(t, out.String(), "skuidCloudDataService")
+	assert.Regexp(t, `total\s+0`, out.String())
+}
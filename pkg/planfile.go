@@ -0,0 +1,206 @@
+package pkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+const (
+	// PLAN_FILE_EXTENSION is the conventional extension for saved deploy plans
+	PLAN_FILE_EXTENSION = ".skuidplan"
+	PLAN_FILE_VERSION   = 1
+)
+
+// SavedDeployPlan is a deployment plan saved for approval, along with what it
+// was planned against, so that exactly what was approved can be applied later
+type SavedDeployPlan struct {
+	Version int               `json:"version"`
+	Host    string            `json:"host"`
+	Created time.Time         `json:"created"`
+	Plans   NlxDynamicPlanMap `json:"plans"`
+	// Hashes are the sha256 of every file in each plan's archive, after
+	// variables are substituted, by plan name and then path
+	Hashes map[string]map[string]string `json:"hashes"`
+}
+
+// PlanChangedError lists the archived files that differ from a saved plan
+type PlanChangedError struct {
+	Changed []string
+	Added   []string
+	Removed []string
+}
+
+func (e PlanChangedError) Error() string {
+	var differences []string
+	for _, difference := range []struct {
+		description string
+		paths       []string
+	}{
+		{"changed", e.Changed},
+		{"added", e.Added},
+		{"removed", e.Removed},
+	} {
+		if len(difference.paths) > 0 {
+			differences = append(differences, fmt.Sprintf("%v %v", difference.description, strings.Join(difference.paths, ", ")))
+		}
+	}
+	return fmt.Sprintf("local files have changed since the plan was made: %v", strings.Join(differences, "; "))
+}
+
+// ArchiveHashes returns the sha256 of every file in a zip archive, by path
+func ArchiveHashes(payload []byte) (hashes map[string]string, err error) {
+	var reader *zip.Reader
+	if reader, err = zip.NewReader(bytes.NewReader(payload), int64(len(payload))); err != nil {
+		return
+	}
+
+	hashes = make(map[string]string, len(reader.File))
+	for _, file := range reader.File {
+		var contents io.ReadCloser
+		if contents, err = file.Open(); err != nil {
+			return
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, contents)
+		contents.Close()
+		if err != nil {
+			return
+		}
+		hashes[file.Name] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return
+}
+
+// planHashes archives each plan's files from the directory and hashes them
+func planHashes(plans NlxDynamicPlanMap, targetDir string, variables util.Variables) (hashes map[string]map[string]string, err error) {
+	hashes = make(map[string]map[string]string, len(plans))
+	for name, plan := range plans {
+		var payload []byte
+		if payload, err = ArchiveWithVariables(targetDir, &plan.Metadata, variables); err != nil {
+			return
+		}
+		if hashes[name], err = ArchiveHashes(payload); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// NewSavedDeployPlan records the plans and the hashes of the files they would
+// deploy from the directory
+func NewSavedDeployPlan(host string, plans NlxDynamicPlanMap, targetDir string, variables util.Variables) (saved SavedDeployPlan, err error) {
+	saved = SavedDeployPlan{
+		Version: PLAN_FILE_VERSION,
+		Host:    host,
+		Created: time.Now().UTC(),
+		Plans:   plans,
+	}
+	saved.Hashes, err = planHashes(plans, targetDir, variables)
+	return
+}
+
+// Write saves the plan to a file
+func (saved SavedDeployPlan) Write(path string) (err error) {
+	var data []byte
+	if data, err = json.MarshalIndent(saved, "", "\t"); err != nil {
+		return
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadSavedDeployPlan reads a plan saved by Write
+func ReadSavedDeployPlan(path string) (saved SavedDeployPlan, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &saved); err != nil {
+		err = fmt.Errorf("unable to read plan %v: %w", path, err)
+		return
+	}
+	if saved.Version != PLAN_FILE_VERSION {
+		err = fmt.Errorf("plan %v has version %v, expected %v", path, saved.Version, PLAN_FILE_VERSION)
+	}
+	return
+}
+
+// Verify checks that the plan is for the host and that the files it would
+// deploy from the directory haven't changed since it was saved. Any change
+// is a PlanChangedError.
+func (saved SavedDeployPlan) Verify(host, targetDir string, variables util.Variables) (err error) {
+	if !sameHost(saved.Host, host) {
+		return fmt.Errorf("plan was made for %v, not %v", saved.Host, host)
+	}
+
+	var hashes map[string]map[string]string
+	if hashes, err = planHashes(saved.Plans, targetDir, variables); err != nil {
+		return
+	}
+
+	changed := make(map[string]bool)
+	added := make(map[string]bool)
+	removed := make(map[string]bool)
+	for name := range saved.Plans {
+		changedFiles(saved.Hashes[name], hashes[name], changed, added, removed)
+	}
+	return planChangedError(changed, added, removed)
+}
+
+// changedFiles compares the planned hashes of files with their current
+// hashes, and adds the paths that differ to the changed, added and removed
+// sets
+func changedFiles(planned, current map[string]string, changed, added, removed map[string]bool) {
+	for path, hash := range planned {
+		if currentHash, ok := current[path]; !ok {
+			removed[path] = true
+		} else if currentHash != hash {
+			changed[path] = true
+		}
+	}
+	for path := range current {
+		if _, ok := planned[path]; !ok {
+			added[path] = true
+		}
+	}
+}
+
+// planChangedError is a PlanChangedError for the paths, or nil if there
+// aren't any
+func planChangedError(changed, added, removed map[string]bool) error {
+	if len(changed) == 0 && len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	sortedPaths := func(paths map[string]bool) (sorted []string) {
+		for path := range paths {
+			sorted = append(sorted, path)
+		}
+		sort.Strings(sorted)
+		return
+	}
+	return PlanChangedError{
+		Changed: sortedPaths(changed),
+		Added:   sortedPaths(added),
+		Removed: sortedPaths(removed),
+	}
+}
+
+// sameHost compares hosts the way they're given on the command line, with or
+// without a scheme
+func sameHost(a, b string) bool {
+	normalize := func(host string) string {
+		host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+		return strings.TrimSuffix(host, "/")
+	}
+	return strings.EqualFold(normalize(a), normalize(b))
+}
@@ -0,0 +1,109 @@
+package pkg_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestSavedDeployPlan(t *testing.T) {
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Metadata: pkg.NlxMetadata{Pages: []string{"Home", "Other"}}},
+		pkg.DATA_PLAN_KEY:     pkg.NlxPlan{Metadata: pkg.NlxMetadata{DataSources: []string{"Orders"}}},
+	}
+	variables := util.Variables{"URL": "https://prod"}
+
+	for _, tc := range []struct {
+		description string
+		host        string
+		variables   util.Variables
+		change      func(dir string)
+		expected    string
+	}{
+		{
+			description: "unchanged",
+			host:        "https://example.skuidsite.com",
+			variables:   variables,
+		},
+		{
+			description: "host without scheme",
+			host:        "example.skuidsite.com/",
+			variables:   variables,
+		},
+		{
+			description: "other host",
+			host:        "https://other.skuidsite.com",
+			variables:   variables,
+			expected:    "plan was made for https://example.skuidsite.com, not https://other.skuidsite.com",
+		},
+		{
+			description: "other variables",
+			host:        "https://example.skuidsite.com",
+			variables:   util.Variables{"URL": "https://test"},
+			expected:    "local files have changed since the plan was made: changed datasources/Orders.json",
+		},
+		{
+			description: "files changed",
+			host:        "https://example.skuidsite.com",
+			variables:   variables,
+			change: func(dir string) {
+				_ = os.WriteFile(filepath.Join(dir, "pages", "Home.json"), []byte(`{"name":"Home","title":"New"}`), 0644)
+				_ = os.WriteFile(filepath.Join(dir, "pages", "Other.json"), []byte(`{"name":"Other"}`), 0644)
+				_ = os.Remove(filepath.Join(dir, "pages", "Home.xml"))
+			},
+			// archive paths use forward slashes on every platform
+			expected: "local files have changed since the plan was made: changed pages/Home.json; added pages/Other.json; removed pages/Home.xml",
+		},
+		{
+			description: "files outside the plan changed",
+			host:        "https://example.skuidsite.com",
+			variables:   variables,
+			change: func(dir string) {
+				_ = os.WriteFile(filepath.Join(dir, "pages", "Unplanned.json"), []byte(`{"name":"Unplanned"}`), 0644)
+			},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			dir := writeTestSite(t, map[string]string{
+				"datasources/Orders.json": `{"name":"Orders","url":"${SKUID_ENV:URL}"}`,
+				"pages/Home.json":         `{"name":"Home"}`,
+				"pages/Home.xml":          `<skuidpage/>`,
+			})
+			planFile := filepath.Join(t.TempDir(), "plan"+pkg.PLAN_FILE_EXTENSION)
+
+			saved, err := pkg.NewSavedDeployPlan("https://example.skuidsite.com", plans, dir, variables)
+			assert.NoError(t, err)
+			assert.Len(t, saved.Hashes[pkg.METADATA_PLAN_KEY], 2)
+			assert.Len(t, saved.Hashes[pkg.DATA_PLAN_KEY], 1)
+			assert.NoError(t, saved.Write(planFile))
+
+			loaded, err := pkg.ReadSavedDeployPlan(planFile)
+			assert.NoError(t, err)
+			assert.Equal(t, saved.Hashes, loaded.Hashes)
+			assert.Equal(t, saved.Plans, loaded.Plans)
+
+			if tc.change != nil {
+				tc.change(dir)
+			}
+			err = loaded.Verify(tc.host, dir, tc.variables)
+			if tc.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestReadSavedDeployPlanVersion(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan"+pkg.PLAN_FILE_EXTENSION)
+	assert.NoError(t, os.WriteFile(planFile, []byte(`{"version":99}`), 0644))
+
+	_, err := pkg.ReadSavedDeployPlan(planFile)
+	assert.EqualError(t, err, "plan "+planFile+" has version 99, expected 1")
+}
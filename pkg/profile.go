@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+
+	"github.com/skuid/skuid-cli/pkg/constants"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// Profile is a named group of settings in the .skuid config file:
+//
+//	profiles:
+//	  prod:
+//	    host: https://prod.skuidsite.com
+//	    protected: true
+//	    envFile: ./env/prod.env
+//	    variables:
+//	      DATASOURCE_URL: https://api.example.com
+type Profile struct {
+	Name string `mapstructure:"-"`
+	// Host is deployed to when no --host is given
+	Host string `mapstructure:"host"`
+	// Protected deploys have to be confirmed by typing the host name, and
+	// are refused without --yes when there's no one to ask
+	Protected bool `mapstructure:"protected"`
+	// EnvFile is read for variables before Variables are applied
+	EnvFile string `mapstructure:"envFile"`
+	// Variables are the values for ${SKUID_ENV:NAME} placeholders. The config
+	// file's keys aren't case sensitive, so neither are these names.
+	Variables map[string]string `mapstructure:"variables"`
+}
+
+// GetProfile reads a profile from the .skuid config file
+func GetProfile(name string) (profile Profile, err error) {
+	key := constants.CONFIG_PROFILES + "." + name
+	if !viper.IsSet(key) {
+		err = fmt.Errorf("no profile named %v in the config file", name)
+		return
+	}
+
+	if err = viper.UnmarshalKey(key, &profile); err != nil {
+		err = fmt.Errorf("unable to read profile %v: %w", name, err)
+		return
+	}
+	profile.Name = name
+
+	return
+}
+
+// GetProfiles reads every profile in the .skuid config file, by name
+func GetProfiles() (profiles []Profile, err error) {
+	names := make([]string, 0)
+	for name := range viper.GetStringMap(constants.CONFIG_PROFILES) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var profile Profile
+		if profile, err = GetProfile(name); err != nil {
+			return
+		}
+		profiles = append(profiles, profile)
+	}
+	return
+}
+
+// ProtectedHost returns true if a protected profile in the .skuid config file
+// has the host, so that deploying to a protected site with --host instead of
+// its profile is still protected. If the profiles can't be read, there's no
+// telling, so the host is protected.
+func ProtectedHost(host string) bool {
+	profiles, err := GetProfiles()
+	if err != nil {
+		return true
+	}
+	for _, profile := range profiles {
+		if profile.Protected && profile.Host != "" && sameHost(profile.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetVariables returns the profile's variables, including those in its env file
+func (profile Profile) GetVariables() (variables util.Variables, err error) {
+	variables = util.Variables{}
+	if profile.EnvFile != "" {
+		if variables, err = util.ReadVariablesFile(profile.EnvFile); err != nil {
+			return
+		}
+	}
+	return variables.Merge(util.Variables(profile.Variables)), nil
+}
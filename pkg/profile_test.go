@@ -0,0 +1,42 @@
+package pkg_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestGetProfile(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "prod.env")
+	assert.NoError(t, os.WriteFile(envFile, []byte("URL=https://file\nCLIENT_ID=file\n"), 0644))
+
+	viper.Set("profiles", map[string]interface{}{
+		"prod": map[string]interface{}{
+			"host":      "https://prod.skuidsite.com",
+			"protected": true,
+			"envFile":   envFile,
+			"variables": map[string]interface{}{"CLIENT_ID": "config"},
+		},
+	})
+	defer viper.Reset()
+
+	profile, err := pkg.GetProfile("prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", profile.Name)
+	assert.Equal(t, "https://prod.skuidsite.com", profile.Host)
+	assert.True(t, profile.Protected)
+
+	variables, err := profile.GetVariables()
+	assert.NoError(t, err)
+	// the profile's own variables override its env file
+	assert.Equal(t, util.Variables{"URL": "https://file", "client_id": "config"}, variables)
+
+	_, err = pkg.GetProfile("missing")
+	assert.Error(t, err)
+}
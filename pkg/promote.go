@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/skuid/skuid-cli/pkg/constants"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// PromoteSite finds the site that a promote's --from or --to refers to: the
+// host of the profile with that name, if there is one, otherwise the host
+// itself
+func PromoteSite(value string) (site DeployTarget, err error) {
+	if value = strings.TrimSpace(value); value == "" {
+		err = fmt.Errorf("no site given")
+		return
+	}
+
+	if !viper.IsSet(constants.CONFIG_PROFILES + "." + value) {
+		site.Host = value
+		return
+	}
+
+	var profile Profile
+	if profile, err = GetProfile(value); err != nil {
+		return
+	}
+	if profile.Host == "" {
+		err = fmt.Errorf("profile %v has no host", profile.Name)
+		return
+	}
+	site = DeployTarget{Host: profile.Host, Profile: &profile}
+	return
+}
+
+// PromoteSites finds the sites a promote goes from and to, which have to be
+// different
+func PromoteSites(from, to string) (source, target DeployTarget, err error) {
+	if source, err = PromoteSite(from); err != nil {
+		return
+	}
+	if target, err = PromoteSite(to); err != nil {
+		return
+	}
+	if sameHost(source.Host, target.Host) {
+		err = fmt.Errorf("can't promote %v to itself", target.Host)
+	}
+	return
+}
+
+// ValidateMetadataTypes returns an error for any type that isn't a metadata
+// type directory name
+func ValidateMetadataTypes(types []string) error {
+	known := GetMetadataTypeDirNames()
+	for _, metadataType := range types {
+		if !util.StringSliceContainsKey(known, metadataType) {
+			return fmt.Errorf("unknown metadata type %v, expected one of [ %v ]", metadataType, strings.Join(known, " | "))
+		}
+	}
+	return nil
+}
+
+// MetadataTypeFilter returns a filter keeping only the files of the metadata
+// types, by their directory names. With no types, every file is kept.
+func MetadataTypeFilter(types []string) (keep func(string) bool, err error) {
+	keep = func(string) bool { return true }
+	if len(types) == 0 {
+		return
+	}
+	if err = ValidateMetadataTypes(types); err != nil {
+		return
+	}
+
+	keep = func(item string) bool {
+		metadataType, _, ok := EntityFromPath(item)
+		return ok && util.StringSliceContainsKey(types, metadataType)
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestPromoteSites(t *testing.T) {
+	viper.Set("profiles", map[string]interface{}{
+		"uat":     map[string]interface{}{"host": "https://uat.skuidsite.com"},
+		"prod":    map[string]interface{}{"host": "https://prod.skuidsite.com", "protected": true},
+		"nohost":  map[string]interface{}{"protected": true},
+		"prodtoo": map[string]interface{}{"host": "prod.skuidsite.com/"},
+	})
+	defer viper.Reset()
+
+	for _, tc := range []struct {
+		description     string
+		from, to        string
+		wantFrom        string
+		wantTo          string
+		wantToProfile   string
+		wantToProtected bool
+		wantError       bool
+	}{
+		{
+			description:     "profiles",
+			from:            "uat",
+			to:              "prod",
+			wantFrom:        "https://uat.skuidsite.com",
+			wantTo:          "https://prod.skuidsite.com",
+			wantToProfile:   "prod",
+			wantToProtected: true,
+		},
+		{
+			description: "hosts",
+			from:        "uat.skuidsite.com",
+			to:          "https://dev.skuidsite.com",
+			wantFrom:    "uat.skuidsite.com",
+			wantTo:      "https://dev.skuidsite.com",
+		},
+		{
+			description:     "profile and host",
+			from:            "dev.skuidsite.com",
+			to:              "prod",
+			wantFrom:        "dev.skuidsite.com",
+			wantTo:          "https://prod.skuidsite.com",
+			wantToProfile:   "prod",
+			wantToProtected: true,
+		},
+		{
+			description: "profile without a host",
+			from:        "uat",
+			to:          "nohost",
+			wantError:   true,
+		},
+		{
+			description: "no site",
+			from:        "uat",
+			to:          " ",
+			wantError:   true,
+		},
+		{
+			description: "same site",
+			from:        "prod",
+			to:          "prodtoo",
+			wantError:   true,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			from, to, err := pkg.PromoteSites(tc.from, tc.to)
+			if tc.wantError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantFrom, from.Host)
+			assert.Equal(t, tc.wantTo, to.Host)
+			assert.Equal(t, tc.wantToProfile, to.ProfileName())
+			assert.Equal(t, tc.wantToProtected, to.Protected())
+		})
+	}
+}
+
+func TestMetadataTypeFilter(t *testing.T) {
+	keep, err := pkg.MetadataTypeFilter(nil)
+	assert.NoError(t, err)
+	assert.True(t, keep("pages/home.json"))
+	assert.True(t, keep("readme.md"))
+
+	keep, err = pkg.MetadataTypeFilter([]string{"pages", "apps"})
+	assert.NoError(t, err)
+	for _, tc := range []struct {
+		item string
+		want bool
+	}{
+		{"pages/home.json", true},
+		{"pages/home.xml", true},
+		{"apps/myapp.json", true},
+		{"datasources/db.json", false},
+		{"readme.md", false},
+	} {
+		assert.Equal(t, tc.want, keep(tc.item), tc.item)
+	}
+
+	_, err = pkg.MetadataTypeFilter([]string{"pages", "widgets"})
+	assert.Error(t, err)
+}
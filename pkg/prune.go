@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// unprunableTypes are never deleted from a site, even when they're missing
+// locally, since a site can't be without them
+var unprunableTypes = []string{"site"}
+
+// LocalMetadata returns every entity with files in the directory, including
+// those that .skuidignore excludes, so that ignored entities are never pruned
+func LocalMetadata(directory string) (metadata NlxMetadata, err error) {
+	var paths []string
+	err = filepath.Walk(directory, func(filePath string, fileInfo os.FileInfo, e error) (err error) {
+		if e != nil {
+			return e
+		}
+
+		var relativePath string
+		if relativePath, err = filepath.Rel(directory, filePath); err != nil {
+			return
+		}
+
+		if strings.HasPrefix(relativePath, ".") && relativePath != "." {
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return
+		}
+
+		if !fileInfo.IsDir() {
+			paths = append(paths, relativePath)
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	return MetadataFromPaths(paths), nil
+}
+
+// PruneMetadata returns the entities on the site, from its retrieval plan,
+// that aren't in the local metadata. Only the metadata types given are
+// pruned, or every type if none are. If there's a scope, only its entities
+// are pruned, whatever else the site's plan has.
+func PruneMetadata(remote NlxPlanPayload, local NlxMetadata, types []string, scope *NlxMetadata) (prune NlxMetadata) {
+	if len(types) == 0 {
+		types = GetMetadataTypeDirNames()
+	}
+	var prunable []string
+	for _, metadataType := range types {
+		if !util.StringSliceContainsKey(unprunableTypes, metadataType) {
+			prunable = append(prunable, metadataType)
+		}
+	}
+
+	prune = remote.Metadata().Difference(local).OnlyTypes(prunable)
+	if scope != nil {
+		prune = prune.Intersect(*scope)
+	}
+	return
+}
+
+// AppEntities returns an app and the pages that its definitions route to,
+// e.g. the site's and the directory's versions of the app's .json file
+func AppEntities(appName string, definitions ...[]byte) (entities NlxMetadata) {
+	entities.Apps = []string{appName}
+	for _, definition := range definitions {
+		var routes appRoutes
+		if json.Unmarshal(definition, &routes) != nil {
+			continue
+		}
+		for _, route := range routes.Routes {
+			if route.Page != "" && !util.StringSliceContainsKey(entities.Pages, route.Page) {
+				entities.Pages = append(entities.Pages, route.Page)
+			}
+		}
+	}
+	return
+}
+
+// appScope returns the app's entities, from the app on the site and in the
+// directory, so that a prune with an app filter can't reach beyond the app
+// even if the site's plan does
+func appScope(auth *Authorization, remote NlxPlanPayload, appName, targetDir string) (scope NlxMetadata, err error) {
+	appFile := filepath.Join("apps", appName+".json")
+
+	var definitions [][]byte
+	if local, readErr := os.ReadFile(filepath.Join(targetDir, appFile)); readErr == nil {
+		definitions = append(definitions, local)
+	}
+
+	plans := remote.Restrict(NlxMetadata{Apps: []string{appName}})
+	if plans.MetadataService != nil || plans.CloudDataService != nil {
+		var results []NlxRetrievalResult
+		if _, results, err = ExecuteRetrieval(auth, plans); err != nil {
+			return
+		}
+
+		var retrieved string
+		if retrieved, err = os.MkdirTemp("", "skuid-prune-app"); err != nil {
+			return
+		}
+		defer os.RemoveAll(retrieved)
+
+		util.ResetPathMap()
+		for _, result := range results {
+			if err = util.WriteResultsToDisk(retrieved, util.WritePayload{
+				PlanName: result.PlanName,
+				PlanData: result.Data,
+			}); err != nil {
+				return
+			}
+		}
+
+		if site, readErr := os.ReadFile(filepath.Join(retrieved, appFile)); readErr == nil {
+			definitions = append(definitions, site)
+		}
+	}
+
+	return AppEntities(appName, definitions...), nil
+}
+
+// GetPrunePlan finds the entities on the site that the filter covers, of the
+// metadata types given, that the directory doesn't have. The filter is
+// applied here too, rather than trusting the site to have applied it: with
+// page names (nil for no page filter) only those pages are listed, and with
+// an app only the app and its pages are. Nothing is deleted, since the
+// services don't plan deletes.
+func GetPrunePlan(auth *Authorization, filter *NlxPlanFilter, pageNames []string, targetDir string, types []string) (prune NlxMetadata, err error) {
+	var local NlxMetadata
+	if local, err = LocalMetadata(targetDir); err != nil {
+		return
+	}
+	// an empty or mistyped directory would otherwise delete the whole site
+	if local.Count() == 0 {
+		err = fmt.Errorf("refusing to prune: there's no metadata in %v", targetDir)
+		return
+	}
+
+	var remote NlxPlanPayload
+	if _, remote, err = GetRetrievePlan(auth, filter); err != nil {
+		return
+	}
+
+	var scope *NlxMetadata
+	if pageNames != nil {
+		scope = &NlxMetadata{Pages: pageNames}
+	}
+	if filter != nil && filter.AppName != "" {
+		var app NlxMetadata
+		if app, err = appScope(auth, remote, filter.AppName, targetDir); err != nil {
+			return
+		}
+		if scope != nil {
+			app = scope.Intersect(app)
+		}
+		scope = &app
+	}
+
+	prune = PruneMetadata(remote, local, types, scope)
+	return
+}
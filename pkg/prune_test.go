@@ -0,0 +1,196 @@
+package pkg_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestPruneMetadata(t *testing.T) {
+	remote := pkg.NlxPlanPayload{
+		MetadataService: &pkg.NlxPlan{
+			Metadata: pkg.NlxMetadata{
+				Pages:       []string{"Home", "Old"},
+				Apps:        []string{"Sales", "Retired"},
+				DataSources: []string{"Orders"},
+				Site:        []string{"site"},
+			},
+		},
+		CloudDataService: &pkg.NlxPlan{
+			Metadata: pkg.NlxMetadata{DataSources: []string{"Orders", "Legacy"}},
+		},
+	}
+	local := pkg.NlxMetadata{
+		Pages:       []string{"Home", "New"},
+		Apps:        []string{"Sales"},
+		DataSources: []string{"Orders"},
+	}
+
+	for _, tc := range []struct {
+		description string
+		types       []string
+		scope       *pkg.NlxMetadata
+		expected    pkg.NlxMetadata
+	}{
+		{
+			description: "every type, but never the site",
+			expected: pkg.NlxMetadata{
+				Apps:        []string{"Retired"},
+				DataSources: []string{"Legacy"},
+				Pages:       []string{"Old"},
+			},
+		},
+		{
+			description: "only the types asked for",
+			types:       []string{"pages", "site"},
+			expected:    pkg.NlxMetadata{Pages: []string{"Old"}},
+		},
+		{
+			description: "nothing missing",
+			types:       []string{"themes"},
+			expected:    pkg.NlxMetadata{},
+		},
+		{
+			description: "only the pages in scope",
+			scope:       &pkg.NlxMetadata{Pages: []string{"Home"}},
+			expected:    pkg.NlxMetadata{},
+		},
+		{
+			description: "only the app's entities",
+			scope:       &pkg.NlxMetadata{Apps: []string{"Retired"}, Pages: []string{"Old"}},
+			expected: pkg.NlxMetadata{
+				Apps:  []string{"Retired"},
+				Pages: []string{"Old"},
+			},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			prune := pkg.PruneMetadata(remote, local, tc.types, tc.scope)
+			assert.Equal(t, tc.expected, prune)
+
+			// each service only deletes its own entities
+			plans := remote.Restrict(prune)
+			if len(tc.expected.DataSources) > 0 {
+				assert.Nil(t, plans.MetadataService.Metadata.DataSources)
+				assert.Equal(t, []string{"Legacy"}, plans.CloudDataService.Metadata.DataSources)
+			}
+		})
+	}
+}
+
+func TestLocalMetadata(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"pages/Home.json":              `{}`,
+		"pages/Home.xml":               `<skuidpage/>`,
+		"pages/Draft.json":             `{}`,
+		"apps/Sales.json":              `{}`,
+		".skuidignore":                 "pages/Draft.json\n",
+		".skuid/snapshots/x/apps/Old":  `{}`,
+		"componentpacks/charts/a.json": `{}`,
+	} {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	// ignored entities are local, so they're never pruned
+	local, err := pkg.LocalMetadata(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.NlxMetadata{
+		Apps:           []string{"Sales"},
+		ComponentPacks: []string{"charts"},
+		Pages:          []string{"Draft", "Home"},
+	}, local)
+}
+
+func TestAppEntities(t *testing.T) {
+	site := []byte(`{"name":"Sales","routes":[{"page":"Home"},{"page":"Old"}]}`)
+	local := []byte(`{"name":"Sales","routes":[{"page":"Home"},{"page":"New"},{"path":"/external"}]}`)
+
+	assert.Equal(t, pkg.NlxMetadata{
+		Apps:  []string{"Sales"},
+		Pages: []string{"Home", "Old", "New"},
+	}, pkg.AppEntities("Sales", site, local, []byte(`{`)))
+}
+
+func TestGetPrunePlan(t *testing.T) {
+	// the site ignores the filter, and plans the whole site
+	retrieved := writeTestSite(t, map[string]string{
+		"apps/Sales.json": `{"name":"Sales","routes":[{"page":"Home"},{"page":"Old"}]}`,
+	})
+	retrievedApp, err := pkg.Archive(retrieved, nil)
+	assert.NoError(t, err)
+
+	var retrievals []string
+	auth := testSite(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/metadata/retrieve/plan":
+			_, _ = w.Write([]byte(`{"skuidMetadataService":{"url":"/metadata/retrieve","type":"metadataService","metadata":{
+				"apps":["Sales","Retired"],
+				"pages":["Home","Old","Other"]
+			}}}`))
+		case "/api/v2/metadata/retrieve":
+			body, _ := io.ReadAll(r.Body)
+			retrievals = append(retrievals, string(body))
+			_, _ = w.Write(retrievedApp)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	dir := writeTestSite(t, map[string]string{
+		"apps/Sales.json": `{"name":"Sales","routes":[{"page":"Home"}]}`,
+		"pages/Home.json": `{"name":"Home"}`,
+		"pages/Home.xml":  `<skuidpage/>`,
+	})
+
+	for _, tc := range []struct {
+		description string
+		filter      *pkg.NlxPlanFilter
+		pageNames   []string
+		expected    pkg.NlxMetadata
+	}{
+		{
+			description: "no filter",
+			expected: pkg.NlxMetadata{
+				Apps:  []string{"Retired"},
+				Pages: []string{"Old", "Other"},
+			},
+		},
+		{
+			description: "pages",
+			filter:      &pkg.NlxPlanFilter{PageNames: []string{"Other"}},
+			pageNames:   []string{"Other"},
+			expected:    pkg.NlxMetadata{Pages: []string{"Other"}},
+		},
+		{
+			description: "modules without pages in the directory",
+			filter:      &pkg.NlxPlanFilter{Modules: []string{"sales"}},
+			pageNames:   []string{},
+			expected:    pkg.NlxMetadata{},
+		},
+		{
+			description: "app",
+			filter:      &pkg.NlxPlanFilter{AppName: "Sales"},
+			expected:    pkg.NlxMetadata{Pages: []string{"Old"}},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			prune, err := pkg.GetPrunePlan(auth, tc.filter, tc.pageNames, dir, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, prune)
+		})
+	}
+
+	// only the app was retrieved, to find its pages
+	if assert.Len(t, retrievals, 1) {
+		assert.Contains(t, retrievals[0], `"apps":["Sales"]`)
+	}
+}
@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// DeployPlanReport describes what a deploy would do without doing it
+type DeployPlanReport struct {
+	Host         string                    `json:"host"`
+	PayloadBytes int                       `json:"payloadBytes"`
+	Services     []DeployPlanServiceReport `json:"services"`
+	Warnings     []string                  `json:"warnings"`
+	// Prune is what would be deleted from the site, when pruning
+	Prune *NlxMetadata `json:"prune,omitempty"`
+}
+
+type DeployPlanServiceReport struct {
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	Endpoint     string      `json:"endpoint"`
+	PayloadBytes int         `json:"payloadBytes"`
+	Metadata     NlxMetadata `json:"metadata"`
+	Warnings     []string    `json:"warnings"`
+}
+
+// SortedPlanNames returns the names of the plans in the order they're
+// deployed: the metadata service, then the data service, then anything else
+func SortedPlanNames(plans NlxDynamicPlanMap) (names []string) {
+	for _, name := range []string{METADATA_PLAN_KEY, DATA_PLAN_KEY} {
+		if _, ok := plans[name]; ok {
+			names = append(names, name)
+		}
+	}
+
+	var others []string
+	for name := range plans {
+		if name != METADATA_PLAN_KEY && name != DATA_PLAN_KEY {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+
+	return append(names, others...)
+}
+
+// NewDeployPlanReport builds the report for a deploy plan. Each service's
+// payload is archived the same way ExecuteDeployPlan would archive it.
+func NewDeployPlanReport(auth *Authorization, plans NlxDynamicPlanMap, targetDir string, payload []byte, variables util.Variables) (report DeployPlanReport, err error) {
+	report = DeployPlanReport{
+		Host:         auth.Host,
+		PayloadBytes: len(payload),
+		Services:     []DeployPlanServiceReport{},
+		Warnings:     []string{},
+	}
+
+	for _, name := range SortedPlanNames(plans) {
+		plan := plans[name]
+
+		var servicePayload []byte
+		if servicePayload, err = ArchiveWithVariables(targetDir, &plan.Metadata, variables); err != nil {
+			return
+		}
+
+		warnings := plan.Warnings
+		if warnings == nil {
+			warnings = []string{}
+		}
+
+		report.Services = append(report.Services, DeployPlanServiceReport{
+			Name:         name,
+			Type:         plan.Type,
+			Endpoint:     GenerateRoute(auth, plan),
+			PayloadBytes: len(servicePayload),
+			Metadata:     plan.Metadata,
+			Warnings:     warnings,
+		})
+		report.Warnings = append(report.Warnings, warnings...)
+	}
+
+	return
+}
+
+// WriteTable writes a human readable version of the report
+func (report DeployPlanReport) WriteTable(w io.Writer) (err error) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "host\t%v\n", report.Host)
+	fmt.Fprintf(tw, "payload\t%v bytes\n\n", report.PayloadBytes)
+
+	for _, service := range report.Services {
+		fmt.Fprintf(tw, "%v\tendpoint: %v\tpayload: %v bytes\n", service.Name, service.Endpoint, service.PayloadBytes)
+		if err = writeMetadataRows(tw, service.Metadata); err != nil {
+			return
+		}
+		for _, warning := range service.Warnings {
+			fmt.Fprintf(tw, "warning\t%v\t\n", warning)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	fmt.Fprintf(tw, "warnings\t%v\n", len(report.Warnings))
+
+	if report.Prune != nil {
+		fmt.Fprintln(tw, "\nprune\tdeleted from the site, missing locally\t")
+		if err = writeMetadataRows(tw, *report.Prune); err != nil {
+			return
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,94 @@
+package pkg_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func writeTestSite(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, body := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestNewDeployPlanReport(t *testing.T) {
+	dir := writeTestSite(t, map[string]string{
+		"pages/Home.json":          `{"name":"Home"}`,
+		"pages/Home.xml":           `<skuid__page/>`,
+		"datasources/Orders.json":  `{"name":"Orders"}`,
+		"datasources/Unused.json":  `{"name":"Unused"}`,
+		"apps/Sales.json":          `{"name":"Sales"}`,
+		"permissionsets/Base.json": `{"name":"Base"}`,
+	})
+
+	auth := &pkg.Authorization{Host: "https://example.skuidsite.com"}
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.DATA_PLAN_KEY: pkg.NlxPlan{
+			Host:     "https://warden.example.com",
+			Port:     "8443",
+			Endpoint: "/metadata/deploy",
+			Type:     pkg.DATA_PLAN_TYPE,
+			Metadata: pkg.NlxMetadata{DataSources: []string{"Orders"}},
+			Warnings: []string{"datasource Orders has no permissions"},
+		},
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{
+			Endpoint: "/metadata/deploy",
+			Type:     pkg.METADATA_PLAN_TYPE,
+			Metadata: pkg.NlxMetadata{
+				Pages: []string{"Home"},
+				Apps:  []string{"Sales"},
+			},
+		},
+	}
+
+	report, err := pkg.NewDeployPlanReport(auth, plans, dir, []byte("payload"), nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "https://example.skuidsite.com", report.Host)
+	assert.Equal(t, 7, report.PayloadBytes)
+	assert.Equal(t, []string{"datasource Orders has no permissions"}, report.Warnings)
+	if assert.Len(t, report.Services, 2) {
+		assert.Equal(t, pkg.METADATA_PLAN_KEY, report.Services[0].Name)
+		assert.Equal(t, "https://example.skuidsite.com/api/v2/metadata/deploy", report.Services[0].Endpoint)
+		assert.Equal(t, []string{"Home"}, report.Services[0].Metadata.Pages)
+		assert.Empty(t, report.Services[0].Warnings)
+		assert.Greater(t, report.Services[0].PayloadBytes, 0)
+
+		assert.Equal(t, pkg.DATA_PLAN_KEY, report.Services[1].Name)
+		assert.Equal(t, "https://warden.example.com:8443/api/v2/metadata/deploy", report.Services[1].Endpoint)
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, report.WriteTable(&out))
+	assert.Contains(t, out.String(), "payload  7 bytes")
+	assert.Regexp(t, `pages\s+1\s+Home`, out.String())
+	assert.Regexp(t, `warning\s+datasource Orders has no permissions`, out.String())
+	assert.Regexp(t, `warnings\s+1`, out.String())
+}
+
+func TestSortedPlanNames(t *testing.T) {
+	assert.Equal(t,
+		[]string{pkg.METADATA_PLAN_KEY, pkg.DATA_PLAN_KEY, "aOther", "zOther"},
+		pkg.SortedPlanNames(pkg.NlxDynamicPlanMap{
+			"zOther":              {},
+			pkg.DATA_PLAN_KEY:     {},
+			"aOther":              {},
+			pkg.METADATA_PLAN_KEY: {},
+		}),
+	)
+	assert.Empty(t, pkg.SortedPlanNames(pkg.NlxDynamicPlanMap{}))
+}
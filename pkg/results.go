@@ -0,0 +1,538 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/skuid/skuid-cli/pkg/errors"
+)
+
+const (
+	DEPLOY_STATUS_DEPLOYED = "deployed"
+	DEPLOY_STATUS_FAILED   = "failed"
+	DEPLOY_STATUS_SKIPPED  = "skipped"
+
+	RESULT_INSERTED  = "inserted"
+	RESULT_UPDATED   = "updated"
+	RESULT_DELETED   = "deleted"
+	RESULT_UNCHANGED = "unchanged"
+)
+
+// DeployTypeResults are the entities of one metadata type, by what the
+// deploy did to them
+type DeployTypeResults struct {
+	Type      string   `json:"type"`
+	Inserted  []string `json:"inserted"`
+	Updated   []string `json:"updated"`
+	Deleted   []string `json:"deleted"`
+	Unchanged []string `json:"unchanged"`
+}
+
+type DeployServiceResults struct {
+	Name   string              `json:"name"`
+	Type   string              `json:"type"`
+	Url    string              `json:"url,omitempty"`
+	Status string              `json:"status"`
+	Error  string              `json:"error,omitempty"`
+	Types  []DeployTypeResults `json:"types"`
+	// Batches are only reported for services deployed in batches, whose
+	// Types are those of every batch that was deployed
+	Batches []DeployBatchResults `json:"batches,omitempty"`
+}
+
+// DeployBatchResults are what one batch of a service's deploy did
+type DeployBatchResults struct {
+	Number int                 `json:"number"`
+	Of     int                 `json:"of"`
+	Status string              `json:"status"`
+	Error  string              `json:"error,omitempty"`
+	Types  []DeployTypeResults `json:"types"`
+}
+
+// DeployResultsReport is what a deploy did to each service
+type DeployResultsReport struct {
+	Host     string                 `json:"host"`
+	Services []DeployServiceResults `json:"services"`
+	// Warnings are the plans' warnings, as the warning policy evaluated them
+	Warnings []DeployWarning `json:"warnings,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	// FailedStage is the stage that failed after every service was
+	// deployed, e.g. the data source sync
+	FailedStage string `json:"failedStage,omitempty"`
+}
+
+// deployResponseChanges is how a service describes the changes to one
+// metadata type, e.g. pliny's permissionSets. Items are names, or objects
+// with a name.
+type deployResponseChanges struct {
+	Inserts   []json.RawMessage `json:"inserts"`
+	Updates   []json.RawMessage `json:"updates"`
+	Deletes   []json.RawMessage `json:"deletes"`
+	Unchanged []json.RawMessage `json:"unchanged"`
+}
+
+func deployResponseItemName(item json.RawMessage) string {
+	var name string
+	if json.Unmarshal(item, &name) == nil {
+		return name
+	}
+	var object struct {
+		Name string `json:"name"`
+		Id   string `json:"id"`
+	}
+	if json.Unmarshal(item, &object) == nil {
+		if object.Name != "" {
+			return object.Name
+		}
+		return object.Id
+	}
+	return string(item)
+}
+
+// deployResponseType maps a response key (permissionSets) to the
+// metadata type directory name (permissionsets)
+func deployResponseType(key string) string {
+	for _, metadataType := range GetMetadataTypeDirNames() {
+		if strings.EqualFold(metadataType, key) {
+			return metadataType
+		}
+	}
+	return strings.ToLower(key)
+}
+
+// ParseDeployResponse reads the changes a service reported for each metadata
+// type. Entities in the plan that the service didn't report changing are
+// unchanged.
+func ParseDeployResponse(plan NlxPlan, response []byte) (types []DeployTypeResults, err error) {
+	byType := make(map[string]*DeployTypeResults)
+	get := func(metadataType string) *DeployTypeResults {
+		if results, ok := byType[metadataType]; ok {
+			return results
+		}
+		results := &DeployTypeResults{
+			Type:      metadataType,
+			Inserted:  []string{},
+			Updated:   []string{},
+			Deleted:   []string{},
+			Unchanged: []string{},
+		}
+		byType[metadataType] = results
+		return results
+	}
+
+	reported := make(map[string]bool)
+
+	if len(bytes.TrimSpace(response)) > 0 {
+		var members map[string]json.RawMessage
+		if err = json.Unmarshal(response, &members); err != nil {
+			err = errors.Error("unable to read the %v deploy response: %v", plan.Type, err)
+			return
+		}
+
+		for key, value := range members {
+			var changes deployResponseChanges
+			if json.Unmarshal(value, &changes) != nil {
+				continue
+			}
+			if changes.Inserts == nil && changes.Updates == nil && changes.Deletes == nil && changes.Unchanged == nil {
+				continue
+			}
+
+			results := get(deployResponseType(key))
+			for _, list := range []struct {
+				items []json.RawMessage
+				names *[]string
+			}{
+				{changes.Inserts, &results.Inserted},
+				{changes.Updates, &results.Updated},
+				{changes.Deletes, &results.Deleted},
+				{changes.Unchanged, &results.Unchanged},
+			} {
+				for _, item := range list.items {
+					name := deployResponseItemName(item)
+					*list.names = append(*list.names, name)
+					reported[entityKey(results.Type, name)] = true
+				}
+			}
+		}
+	}
+
+	for _, metadataType := range GetMetadataTypeDirNames() {
+		names, _ := plan.Metadata.GetFieldValueByName(metadataType)
+		for _, name := range names {
+			if !reported[entityKey(metadataType, name)] {
+				results := get(metadataType)
+				results.Unchanged = append(results.Unchanged, name)
+			}
+		}
+	}
+
+	for _, results := range byType {
+		for _, names := range [][]string{results.Inserted, results.Updated, results.Deleted, results.Unchanged} {
+			sort.Strings(names)
+		}
+		types = append(types, *results)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Type < types[j].Type })
+
+	return
+}
+
+// NewDeployResultsReport builds the report for a deploy from the results of
+// the services that were deployed. If the deploy failed, the first service
+// without results is the one that failed and the rest were skipped. If every
+// service has results, or a stage other than a service's deploy failed (such
+// as the warning policy), that stage is the report's FailedStage. A response
+// that can't be read is recorded as the service's error, since the service
+// was still deployed.
+func NewDeployResultsReport(host string, plans NlxDynamicPlanMap, results []NlxDeploymentResult, deployErr error) (report DeployResultsReport) {
+	report = DeployResultsReport{
+		Host:     host,
+		Services: []DeployServiceResults{},
+	}
+	if deployErr != nil {
+		report.Error = deployErr.Error()
+	}
+
+	byName := make(map[string][]NlxDeploymentResult)
+	for _, result := range results {
+		byName[result.PlanName] = append(byName[result.PlanName], result)
+	}
+
+	// a stage other than a service's deploy, such as the warning policy
+	// before them or the data source sync after them, fails none of them
+	stage := failedStage(deployErr)
+	serviceFailed := stage == "" || stagePlanName(stage) != ""
+
+	failed := false
+	for _, name := range SortedPlanNames(plans) {
+		plan := plans[name]
+		service := DeployServiceResults{
+			Name:  name,
+			Type:  plan.Type,
+			Types: []DeployTypeResults{},
+		}
+
+		planResults := byName[name]
+		for _, result := range planResults {
+			service.Url = result.Url
+			var responseErr string
+			types, err := ParseDeployResponse(result.Plan, result.Data)
+			if err != nil {
+				responseErr = err.Error()
+				service.Error = responseErr
+			}
+			service.Types = mergeTypeResults(service.Types, types)
+			if result.Batch != nil {
+				service.Batches = append(service.Batches, DeployBatchResults{
+					Number: result.Batch.Number,
+					Of:     result.Batch.Of,
+					Status: DEPLOY_STATUS_DEPLOYED,
+					Error:  responseErr,
+					Types:  append([]DeployTypeResults{}, types...),
+				})
+			}
+		}
+
+		// a service deployed in batches failed if any of its batches didn't deploy
+		complete := len(planResults) > 0
+		if last := len(service.Batches) - 1; last >= 0 && service.Batches[last].Number < service.Batches[last].Of {
+			complete = false
+		}
+
+		switch {
+		case complete:
+			service.Status = DEPLOY_STATUS_DEPLOYED
+		case deployErr != nil && serviceFailed && !failed:
+			failed = true
+			service.Status = DEPLOY_STATUS_FAILED
+			service.Error = deployErr.Error()
+			if last := len(service.Batches) - 1; last >= 0 {
+				batch := service.Batches[last]
+				for number := batch.Number + 1; number <= batch.Of; number++ {
+					status, batchErr := DEPLOY_STATUS_SKIPPED, ""
+					if number == batch.Number+1 {
+						status, batchErr = DEPLOY_STATUS_FAILED, deployErr.Error()
+					}
+					service.Batches = append(service.Batches, DeployBatchResults{
+						Number: number,
+						Of:     batch.Of,
+						Status: status,
+						Error:  batchErr,
+						Types:  []DeployTypeResults{},
+					})
+				}
+			}
+		default:
+			service.Status = DEPLOY_STATUS_SKIPPED
+		}
+
+		report.Services = append(report.Services, service)
+	}
+
+	if deployErr != nil && !failed {
+		report.FailedStage = stage
+		if report.FailedStage == "" {
+			report.FailedStage = "deploy"
+		}
+	}
+
+	return
+}
+
+// mergeTypeResults combines the results of each metadata type, from batches
+// of the same service
+func mergeTypeResults(into []DeployTypeResults, from []DeployTypeResults) []DeployTypeResults {
+	for _, results := range from {
+		merged := false
+		for i := range into {
+			if into[i].Type != results.Type {
+				continue
+			}
+			into[i].Inserted = append(into[i].Inserted, results.Inserted...)
+			into[i].Updated = append(into[i].Updated, results.Updated...)
+			into[i].Deleted = append(into[i].Deleted, results.Deleted...)
+			into[i].Unchanged = append(into[i].Unchanged, results.Unchanged...)
+			for _, names := range [][]string{into[i].Inserted, into[i].Updated, into[i].Deleted, into[i].Unchanged} {
+				sort.Strings(names)
+			}
+			merged = true
+			break
+		}
+		if !merged {
+			into = append(into, DeployTypeResults{
+				Type:      results.Type,
+				Inserted:  append([]string{}, results.Inserted...),
+				Updated:   append([]string{}, results.Updated...),
+				Deleted:   append([]string{}, results.Deleted...),
+				Unchanged: append([]string{}, results.Unchanged...),
+			})
+		}
+	}
+	sort.Slice(into, func(i, j int) bool { return into[i].Type < into[j].Type })
+	return into
+}
+
+// Totals returns the number of inserted, updated, deleted and unchanged entities
+func (report DeployResultsReport) Totals() (inserted, updated, deleted, unchanged int) {
+	for _, service := range report.Services {
+		for _, results := range service.Types {
+			inserted += len(results.Inserted)
+			updated += len(results.Updated)
+			deleted += len(results.Deleted)
+			unchanged += len(results.Unchanged)
+		}
+	}
+	return
+}
+
+// WriteTable writes the counts for each service and metadata type
+func (report DeployResultsReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	writeTypes := func(name string, types []DeployTypeResults) {
+		for _, results := range types {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\n",
+				name,
+				results.Type,
+				len(results.Inserted),
+				len(results.Updated),
+				len(results.Deleted),
+				len(results.Unchanged),
+			)
+		}
+	}
+
+	fmt.Fprintln(tw, "SERVICE\tTYPE\tINSERTED\tUPDATED\tDELETED\tUNCHANGED")
+	for _, service := range report.Services {
+		// services deployed in batches are listed batch by batch
+		if len(service.Batches) > 0 {
+			for _, batch := range service.Batches {
+				name := fmt.Sprintf("%v (batch %v of %v)", service.Name, batch.Number, batch.Of)
+				if batch.Status != DEPLOY_STATUS_DEPLOYED {
+					fmt.Fprintf(tw, "%v\t%v\t\t\t\t\n", name, batch.Status)
+					continue
+				}
+				writeTypes(name, batch.Types)
+			}
+			continue
+		}
+		if service.Status != DEPLOY_STATUS_DEPLOYED {
+			fmt.Fprintf(tw, "%v\t%v\t\t\t\t\n", service.Name, service.Status)
+			continue
+		}
+		writeTypes(service.Name, service.Types)
+	}
+	if report.FailedStage != "" {
+		fmt.Fprintf(tw, "%v\t%v\t\t\t\t\n", report.FailedStage, DEPLOY_STATUS_FAILED)
+	}
+
+	inserted, updated, deleted, unchanged := report.Totals()
+	fmt.Fprintf(tw, "total\t\t%v\t%v\t%v\t%v\n", inserted, updated, deleted, unchanged)
+
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(tw, "warning\t%v\t%v\n", warning.Policy, warning.Message)
+	}
+
+	return tw.Flush()
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the report as JUnit XML, with a test suite for each
+// service and a test case for each entity. A failed or skipped service is
+// a single failed or skipped test case.
+func (report DeployResultsReport) WriteJUnit(w io.Writer) (err error) {
+	suites := junitTestSuites{Name: "deploy " + report.Host}
+	suites.add(report.junitSuites("")...)
+	return suites.write(w)
+}
+
+// junitSuites returns a test suite for each service, named with the prefix
+func (report DeployResultsReport) junitSuites(prefix string) (suites []junitTestSuite) {
+	for _, service := range report.Services {
+		suite := junitTestSuite{Name: prefix + service.Name}
+
+		switch service.Status {
+		case DEPLOY_STATUS_FAILED:
+			suite.Cases = append(suite.Cases, junitTestCase{
+				ClassName: service.Name,
+				Name:      "deploy",
+				Failure:   &junitMessage{Message: service.Error},
+			})
+			suite.Failures++
+		case DEPLOY_STATUS_SKIPPED:
+			message := "not deployed because an earlier service failed"
+			if report.FailedStage != "" {
+				message = fmt.Sprintf("not deployed because %v failed", report.FailedStage)
+			}
+			suite.Cases = append(suite.Cases, junitTestCase{
+				ClassName: service.Name,
+				Name:      "deploy",
+				Skipped:   &junitMessage{Message: message},
+			})
+			suite.Skipped++
+		}
+
+		for _, results := range service.Types {
+			for _, list := range []struct {
+				status string
+				names  []string
+			}{
+				{RESULT_INSERTED, results.Inserted},
+				{RESULT_UPDATED, results.Updated},
+				{RESULT_DELETED, results.Deleted},
+				{RESULT_UNCHANGED, results.Unchanged},
+			} {
+				for _, name := range list.names {
+					suite.Cases = append(suite.Cases, junitTestCase{
+						ClassName: results.Type,
+						Name:      name,
+						SystemOut: list.status,
+					})
+				}
+			}
+		}
+
+		suite.Tests = len(suite.Cases)
+		suites = append(suites, suite)
+	}
+
+	// a stage after the services' deploys failed, so the deploy did too
+	if report.FailedStage != "" {
+		suites = append(suites, junitTestSuite{
+			Name:     prefix + report.FailedStage,
+			Tests:    1,
+			Failures: 1,
+			Cases: []junitTestCase{{
+				ClassName: "deploy",
+				Name:      report.FailedStage,
+				Failure:   &junitMessage{Message: report.Error},
+			}},
+		})
+	}
+	return
+}
+
+func (suites *junitTestSuites) add(suite ...junitTestSuite) {
+	for _, s := range suite {
+		suites.Tests += s.Tests
+		suites.Failures += s.Failures
+		suites.Suites = append(suites.Suites, s)
+	}
+}
+
+func (suites junitTestSuites) write(w io.Writer) (err error) {
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	if err = encoder.Encode(suites); err != nil {
+		return
+	}
+	_, err = io.WriteString(w, "\n")
+	return
+}
+
+// writeReportFile writes a report to a file, as JUnit XML if the file name
+// ends in .xml and as json otherwise
+func writeReportFile(path string, report any, writeJUnit func(io.Writer) error) (err error) {
+	var buffer bytes.Buffer
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		err = writeJUnit(&buffer)
+	} else {
+		err = WriteStructured(&buffer, OUTPUT_FORMAT_JSON, report)
+	}
+	if err != nil {
+		return
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return
+		}
+	}
+
+	return os.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+// WriteFile writes the report to a file, as JUnit XML if the file name ends
+// in .xml and as json otherwise
+func (report DeployResultsReport) WriteFile(path string) error {
+	return writeReportFile(path, report, report.WriteJUnit)
+}
@@ -0,0 +1,280 @@
+package pkg_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestParseDeployResponse(t *testing.T) {
+	plan := pkg.NlxPlan{
+		Type: pkg.METADATA_PLAN_TYPE,
+		Metadata: pkg.NlxMetadata{
+			Pages:          []string{"Home", "Old", "Same"},
+			PermissionSets: []string{"Admin", "Base"},
+			Apps:           []string{"Sales"},
+		},
+	}
+
+	for _, tc := range []struct {
+		description string
+		response    string
+		expected    []pkg.DeployTypeResults
+		wantErr     bool
+	}{
+		{
+			description: "pliny permission sets and pages",
+			response: `{
+				"permissionSets": {"inserts": [{"id": "1", "name": "Admin"}], "updates": [{"id": "2", "name": "Base"}], "deletes": []},
+				"pages": {"inserts": ["Home"], "deletes": ["Old"]},
+				"other": "ignored"
+			}`,
+			expected: []pkg.DeployTypeResults{
+				{Type: "apps", Inserted: []string{}, Updated: []string{}, Deleted: []string{}, Unchanged: []string{"Sales"}},
+				{Type: "pages", Inserted: []string{"Home"}, Updated: []string{}, Deleted: []string{"Old"}, Unchanged: []string{"Same"}},
+				{Type: "permissionsets", Inserted: []string{"Admin"}, Updated: []string{"Base"}, Deleted: []string{}, Unchanged: []string{}},
+			},
+		},
+		{
+			description: "empty response",
+			response:    "",
+			expected: []pkg.DeployTypeResults{
+				{Type: "apps", Inserted: []string{}, Updated: []string{}, Deleted: []string{}, Unchanged: []string{"Sales"}},
+				{Type: "pages", Inserted: []string{}, Updated: []string{}, Deleted: []string{}, Unchanged: []string{"Home", "Old", "Same"}},
+				{Type: "permissionsets", Inserted: []string{}, Updated: []string{}, Deleted: []string{}, Unchanged: []string{"Admin", "Base"}},
+			},
+		},
+		{
+			description: "unreadable response",
+			response:    "<html>",
+			wantErr:     true,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			actual, err := pkg.ParseDeployResponse(plan, []byte(tc.response))
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestNewDeployResultsReport(t *testing.T) {
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{
+			Type:     pkg.METADATA_PLAN_TYPE,
+			Metadata: pkg.NlxMetadata{Pages: []string{"Home"}},
+		},
+		pkg.DATA_PLAN_KEY: pkg.NlxPlan{
+			Type:     pkg.DATA_PLAN_TYPE,
+			Metadata: pkg.NlxMetadata{DataSources: []string{"Orders"}},
+		},
+	}
+	results := []pkg.NlxDeploymentResult{
+		{PlanName: pkg.METADATA_PLAN_KEY, Url: "https://example/deploy", Data: []byte(`{"pages":{"updates":["Home"]}}`)},
+	}
+
+	report := pkg.NewDeployResultsReport("https://example", plans, results, errors.New("warden is down"))
+	assert.Equal(t, "warden is down", report.Error)
+	if assert.Len(t, report.Services, 2) {
+		assert.Equal(t, pkg.DEPLOY_STATUS_DEPLOYED, report.Services[0].Status)
+		assert.Equal(t, pkg.DEPLOY_STATUS_FAILED, report.Services[1].Status)
+		assert.Equal(t, "warden is down", report.Services[1].Error)
+		assert.Empty(t, report.Services[1].Types)
+	}
+
+	inserted, updated, deleted, unchanged := report.Totals()
+	assert.Equal(t, []int{0, 1, 0, 0}, []int{inserted, updated, deleted, unchanged})
+
+	var out bytes.Buffer
+	assert.NoError(t, report.WriteTable(&out))
+	assert.Regexp(t, `skuidMetadataService\s+pages\s+0\s+1\s+0\s+0`, out.String())
+	assert.Regexp(t, `skuidCloudDataService\s+failed`, out.String())
+	assert.Regexp(t, `total\s+0\s+1\s+0\s+0`, out.String())
+
+	// nothing after the failed service was deployed
+	report = pkg.NewDeployResultsReport("https://example", plans, nil, errors.New("pliny is down"))
+	assert.Equal(t, pkg.DEPLOY_STATUS_FAILED, report.Services[0].Status)
+	assert.Equal(t, pkg.DEPLOY_STATUS_SKIPPED, report.Services[1].Status)
+}
+
+func TestNewDeployResultsReportBatches(t *testing.T) {
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{
+			Type:     pkg.METADATA_PLAN_TYPE,
+			Metadata: pkg.NlxMetadata{Pages: []string{"Home", "Orders", "Accounts"}},
+		},
+	}
+	batch := func(number int, pages ...string) *pkg.DeployBatch {
+		return &pkg.DeployBatch{Number: number, Of: 3, Metadata: pkg.NlxMetadata{Pages: pages}}
+	}
+	result := func(b *pkg.DeployBatch, response string) pkg.NlxDeploymentResult {
+		return pkg.NlxDeploymentResult{
+			Plan:     pkg.NlxPlan{Type: pkg.METADATA_PLAN_TYPE, Metadata: b.Metadata},
+			PlanName: pkg.METADATA_PLAN_KEY,
+			Data:     []byte(response),
+			Batch:    b,
+		}
+	}
+
+	// the first batch deployed, the second failed and the third was skipped
+	report := pkg.NewDeployResultsReport("https://example", plans, []pkg.NlxDeploymentResult{
+		result(batch(1, "Home"), `{"pages":{"updates":["Home"]}}`),
+	}, errors.New("413 Request Entity Too Large"))
+	if assert.Len(t, report.Services, 1) {
+		service := report.Services[0]
+		assert.Equal(t, pkg.DEPLOY_STATUS_FAILED, service.Status)
+		if assert.Len(t, service.Batches, 3) {
+			assert.Equal(t, pkg.DEPLOY_STATUS_DEPLOYED, service.Batches[0].Status)
+			assert.Equal(t, pkg.DEPLOY_STATUS_FAILED, service.Batches[1].Status)
+			assert.Equal(t, "413 Request Entity Too Large", service.Batches[1].Error)
+			assert.Equal(t, pkg.DEPLOY_STATUS_SKIPPED, service.Batches[2].Status)
+		}
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, report.WriteTable(&out))
+	assert.Regexp(t, `skuidMetadataService \(batch 1 of 3\)\s+pages\s+0\s+1\s+0\s+0`, out.String())
+	assert.Regexp(t, `skuidMetadataService \(batch 2 of 3\)\s+failed`, out.String())
+	assert.Regexp(t, `skuidMetadataService \(batch 3 of 3\)\s+skipped`, out.String())
+
+	// every batch deployed, and the service's results are combined
+	report = pkg.NewDeployResultsReport("https://example", plans, []pkg.NlxDeploymentResult{
+		result(batch(1, "Home"), `{"pages":{"updates":["Home"]}}`),
+		result(batch(2, "Orders"), `{"pages":{"inserts":["Orders"]}}`),
+		result(batch(3, "Accounts"), ``),
+	}, nil)
+	assert.Equal(t, pkg.DEPLOY_STATUS_DEPLOYED, report.Services[0].Status)
+	assert.Equal(t, []pkg.DeployTypeResults{
+		{Type: "pages", Inserted: []string{"Orders"}, Updated: []string{"Home"}, Deleted: []string{}, Unchanged: []string{"Accounts"}},
+	}, report.Services[0].Types)
+}
+
+func TestNewDeployResultsReportFailedStage(t *testing.T) {
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{
+			Type:     pkg.METADATA_PLAN_TYPE,
+			Metadata: pkg.NlxMetadata{Pages: []string{"Home"}},
+		},
+		pkg.DATA_PLAN_KEY: pkg.NlxPlan{
+			Type:     pkg.DATA_PLAN_TYPE,
+			Metadata: pkg.NlxMetadata{DataSources: []string{"Orders"}},
+		},
+	}
+	results := []pkg.NlxDeploymentResult{
+		{PlanName: pkg.METADATA_PLAN_KEY, Data: []byte(`{"pages":{"updates":["Home"]}}`)},
+		{PlanName: pkg.DATA_PLAN_KEY, Data: []byte(`{"dataSources":{"updates":["Orders"]}}`)},
+	}
+
+	// both services deployed, then the data source sync failed
+	report := pkg.NewDeployResultsReport("https://example", plans, results, &pkg.DeployStageError{
+		Stage: pkg.DEPLOY_STAGE_SYNC,
+		Err:   errors.New("sync timed out"),
+	})
+	assert.Equal(t, pkg.DEPLOY_STAGE_SYNC, report.FailedStage)
+	for _, service := range report.Services {
+		assert.Equal(t, pkg.DEPLOY_STATUS_DEPLOYED, service.Status)
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, report.WriteTable(&out))
+	assert.Regexp(t, `sync\s+failed`, out.String())
+
+	out.Reset()
+	assert.NoError(t, report.WriteJUnit(&out))
+	assert.Contains(t, out.String(), `<testsuites name="deploy https://example" tests="3" failures="1">`)
+	assert.Contains(t, out.String(), `<testsuite name="sync" tests="1" failures="1" skipped="0">`)
+	assert.Contains(t, out.String(), `<failure message="sync timed out"></failure>`)
+
+	// the multi-site report fails the site's deploy too
+	out.Reset()
+	multi := pkg.MultiSiteDeployReport{Sites: []pkg.SiteDeployReport{{Host: "https://example", Status: pkg.DEPLOY_STATUS_FAILED, Results: &report}}}
+	assert.NoError(t, multi.WriteJUnit(&out))
+	assert.Contains(t, out.String(), `<testsuite name="https://example sync" tests="1" failures="1" skipped="0">`)
+
+	// an error that isn't from a stage still fails the deploy
+	report = pkg.NewDeployResultsReport("https://example", plans, results, errors.New("unable to save deploy journal"))
+	assert.Equal(t, "deploy", report.FailedStage)
+
+	// the warning policy stopped the deploy before any service was deployed
+	policy, err := pkg.NewWarningPolicy(false, nil, []string{"deprecated"})
+	assert.NoError(t, err)
+	warnings, err := policy.Evaluate([]pkg.DeployWarning{{Message: "Orders is deprecated"}})
+	report = pkg.NewDeployResultsReport("https://example", plans, nil, err)
+	report.Warnings = warnings
+	assert.Equal(t, pkg.DEPLOY_STAGE_WARNINGS, report.FailedStage)
+	for _, service := range report.Services {
+		assert.Equal(t, pkg.DEPLOY_STATUS_SKIPPED, service.Status)
+	}
+
+	out.Reset()
+	assert.NoError(t, report.WriteJUnit(&out))
+	assert.Contains(t, out.String(), `<testsuite name="warnings" tests="1" failures="1" skipped="0">`)
+	assert.Contains(t, out.String(), `<failure message="deployment plan has 1 denied warning(s)"></failure>`)
+	assert.Contains(t, out.String(), `<skipped message="not deployed because warnings failed"></skipped>`)
+}
+
+func TestDeployResultsReportWriteFile(t *testing.T) {
+	report := pkg.DeployResultsReport{
+		Host: "https://example",
+		Services: []pkg.DeployServiceResults{
+			{
+				Name:   pkg.METADATA_PLAN_KEY,
+				Status: pkg.DEPLOY_STATUS_DEPLOYED,
+				Types: []pkg.DeployTypeResults{
+					{Type: "pages", Inserted: []string{"Home"}, Unchanged: []string{"Same"}},
+				},
+			},
+			{Name: pkg.DATA_PLAN_KEY, Status: pkg.DEPLOY_STATUS_FAILED, Error: "warden is down"},
+		},
+	}
+
+	dir := t.TempDir()
+	junitPath := filepath.Join(dir, "reports", "deploy.xml")
+	jsonPath := filepath.Join(dir, "deploy.json")
+	assert.NoError(t, report.WriteFile(junitPath))
+	assert.NoError(t, report.WriteFile(jsonPath))
+
+	data, err := os.ReadFile(junitPath)
+	assert.NoError(t, err)
+
+	var suites struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			Name  string `xml:"name,attr"`
+			Cases []struct {
+				ClassName string `xml:"classname,attr"`
+				Name      string `xml:"name,attr"`
+				Failure   *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"failure"`
+				SystemOut string `xml:"system-out"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	assert.NoError(t, xml.Unmarshal(data, &suites))
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	if assert.Len(t, suites.Suites, 2) {
+		assert.Equal(t, "pages", suites.Suites[0].Cases[0].ClassName)
+		assert.Equal(t, "Home", suites.Suites[0].Cases[0].Name)
+		assert.Equal(t, "inserted", suites.Suites[0].Cases[0].SystemOut)
+		assert.Equal(t, "unchanged", suites.Suites[0].Cases[1].SystemOut)
+		assert.Equal(t, "warden is down", suites.Suites[1].Cases[0].Failure.Message)
+	}
+
+	data, err = os.ReadFile(jsonPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"inserted": [`)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/skuid/skuid-cli/pkg/constants"
 	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
 )
 
 var (
@@ -119,3 +120,33 @@ func ExecuteRetrieval(auth *Authorization, plans NlxPlanPayload) (duration time.
 
 	return
 }
+
+// RetrieveToDirectory gets the retrieval plan for the filter, executes it and
+// writes the results to the directory, the same way the retrieve command does
+func RetrieveToDirectory(auth *Authorization, filter *NlxPlanFilter, directory string) (plans NlxPlanPayload, err error) {
+	if _, plans, err = GetRetrievePlan(auth, filter); err != nil {
+		return
+	}
+
+	var results []NlxRetrievalResult
+	if _, results, err = ExecuteRetrieval(auth, plans); err != nil {
+		return
+	}
+
+	// results are combined with files already written during this retrieval
+	util.ResetPathMap()
+
+	for _, v := range results {
+		if err = util.WriteResultsToDisk(
+			directory,
+			util.WritePayload{
+				PlanName: v.PlanName,
+				PlanData: v.Data,
+			},
+		); err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,310 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gookit/color"
+
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+const (
+	// SNAPSHOTS_DIRECTORY is relative to the deployed directory. It is hidden,
+	// so it is never archived as part of a deploy.
+	SNAPSHOTS_DIRECTORY = ".skuid/snapshots"
+	SNAPSHOT_FILE       = "snapshot.json"
+	SNAPSHOT_METADATA   = "metadata"
+	// SNAPSHOT_NAME_FORMAT is a sortable, file system safe timestamp
+	SNAPSHOT_NAME_FORMAT = "20060102T150405.000Z"
+
+	SNAPSHOT_STATUS_PENDING     = "pending"
+	SNAPSHOT_STATUS_DEPLOYED    = "deployed"
+	SNAPSHOT_STATUS_FAILED      = "failed"
+	SNAPSHOT_STATUS_ROLLED_BACK = "rolledBack"
+)
+
+// Snapshot is the remote version of everything in a deploy plan, retrieved
+// before the deploy so that it can be rolled back, and a record of the deploy
+type Snapshot struct {
+	Name    string    `json:"name"`
+	Host    string    `json:"host"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	// Deployed is everything in the deploy plan
+	Deployed NlxMetadata `json:"deployed"`
+	// Retrieved is everything that existed on the site before the deploy
+	Retrieved NlxMetadata `json:"retrieved"`
+
+	directory string
+}
+
+// SnapshotsDirectory returns where the snapshots for a deployed directory are kept
+func SnapshotsDirectory(targetDir string) string {
+	return filepath.Join(targetDir, filepath.FromSlash(SNAPSHOTS_DIRECTORY))
+}
+
+// NewSnapshot starts a snapshot of the deploy plan's entities for a deployed directory
+func NewSnapshot(targetDir, host string, plans NlxDynamicPlanMap) Snapshot {
+	now := time.Now().UTC()
+	snapshot := Snapshot{
+		Name:    now.Format(SNAPSHOT_NAME_FORMAT),
+		Host:    host,
+		Created: now,
+		Updated: now,
+		Status:  SNAPSHOT_STATUS_PENDING,
+	}
+	for _, name := range SortedPlanNames(plans) {
+		snapshot.Deployed = snapshot.Deployed.Union(plans[name].Metadata)
+	}
+	snapshot.directory = filepath.Join(SnapshotsDirectory(targetDir), snapshot.Name)
+	return snapshot
+}
+
+// MetadataDirectory returns the directory the retrieved metadata is written to
+func (snapshot Snapshot) MetadataDirectory() string {
+	return filepath.Join(snapshot.directory, SNAPSHOT_METADATA)
+}
+
+// New returns the deployed entities that didn't exist before the deploy.
+// Rolling back can't remove them.
+func (snapshot Snapshot) New() NlxMetadata {
+	return snapshot.Deployed.Difference(snapshot.Retrieved)
+}
+
+// Save writes the snapshot record
+func (snapshot *Snapshot) Save() (err error) {
+	snapshot.Updated = time.Now().UTC()
+
+	if err = os.MkdirAll(snapshot.directory, 0755); err != nil {
+		return
+	}
+
+	var data []byte
+	if data, err = json.MarshalIndent(snapshot, "", "\t"); err != nil {
+		return
+	}
+
+	return os.WriteFile(filepath.Join(snapshot.directory, SNAPSHOT_FILE), data, 0644)
+}
+
+// Finish records the outcome of the deploy or rollback
+func (snapshot *Snapshot) Finish(status string, deployErr error) error {
+	snapshot.Status = status
+	snapshot.Error = ""
+	if deployErr != nil {
+		snapshot.Error = deployErr.Error()
+	}
+	return snapshot.Save()
+}
+
+// Restrict limits a retrieval plan to the given entities, dropping any
+// service that has nothing left to retrieve. The full, current version of
+// each entity is retrieved.
+func (plans NlxPlanPayload) Restrict(metadata NlxMetadata) (restricted NlxPlanPayload) {
+	restrict := func(plan *NlxPlan) *NlxPlan {
+		if plan == nil {
+			return nil
+		}
+		copied := *plan
+		copied.Metadata = plan.Metadata.Intersect(metadata)
+		copied.Since = ""
+		if copied.Metadata.Count() == 0 {
+			return nil
+		}
+		return &copied
+	}
+	restricted.MetadataService = restrict(plans.MetadataService)
+	restricted.CloudDataService = restrict(plans.CloudDataService)
+	return
+}
+
+// CreateSnapshot retrieves the current remote version of every entity in the
+// deploy plan and saves it under the deployed directory
+func CreateSnapshot(auth *Authorization, plans NlxDynamicPlanMap, targetDir string) (snapshot Snapshot, err error) {
+	snapshot = NewSnapshot(targetDir, auth.Host, plans)
+
+	var retrievePlan NlxPlanPayload
+	if _, retrievePlan, err = GetRetrievePlan(auth, nil); err != nil {
+		return
+	}
+	retrievePlan = retrievePlan.Restrict(snapshot.Deployed)
+
+	logging.Get().Infof("Snapshotting to %v", color.Cyan.Sprint(snapshot.directory))
+
+	var results []NlxRetrievalResult
+	if _, results, err = ExecuteRetrieval(auth, retrievePlan); err != nil {
+		return
+	}
+
+	util.ResetPathMap()
+
+	for _, result := range results {
+		if err = util.WriteResultsToDisk(
+			snapshot.MetadataDirectory(),
+			util.WritePayload{
+				PlanName: result.PlanName,
+				PlanData: result.Data,
+			},
+		); err != nil {
+			return
+		}
+	}
+
+	if snapshot.Retrieved, err = metadataInDirectory(snapshot.MetadataDirectory()); err != nil {
+		return
+	}
+
+	err = snapshot.Save()
+	return
+}
+
+// metadataInDirectory returns the entities that have files in the directory
+func metadataInDirectory(directory string) (metadata NlxMetadata, err error) {
+	if _, err = os.Stat(directory); os.IsNotExist(err) {
+		// nothing was retrieved
+		return metadata, nil
+	}
+
+	var files map[string]string
+	if files, err = metadataFiles(directory, nil); err != nil {
+		return
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return MetadataFromPaths(paths), nil
+}
+
+// LoadSnapshot reads a snapshot of a deployed directory by name
+func LoadSnapshot(targetDir, name string) (snapshot Snapshot, err error) {
+	directory := filepath.Join(SnapshotsDirectory(targetDir), name)
+
+	var data []byte
+	if data, err = os.ReadFile(filepath.Join(directory, SNAPSHOT_FILE)); err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("no snapshot named %v in %v", name, SnapshotsDirectory(targetDir))
+		}
+		return
+	}
+
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return
+	}
+	snapshot.directory = directory
+
+	return
+}
+
+// ListSnapshots returns the snapshots of a deployed directory, oldest first
+func ListSnapshots(targetDir string) (snapshots []Snapshot, err error) {
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(SnapshotsDirectory(targetDir)); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	// entries are sorted by name, which is the creation time
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		var snapshot Snapshot
+		if snapshot, err = LoadSnapshot(targetDir, entry.Name()); err != nil {
+			logging.Get().Warnf("Skipping snapshot %v: %v", entry.Name(), err)
+			err = nil
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return
+}
+
+// LatestSnapshot returns the most recent snapshot taken before a successful deploy
+func LatestSnapshot(targetDir string) (snapshot Snapshot, err error) {
+	var snapshots []Snapshot
+	if snapshots, err = ListSnapshots(targetDir); err != nil {
+		return
+	}
+
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if snapshots[i].Status == SNAPSHOT_STATUS_DEPLOYED {
+			return snapshots[i], nil
+		}
+	}
+
+	err = fmt.Errorf("no deployed snapshots in %v", SnapshotsDirectory(targetDir))
+	return
+}
+
+// WriteSnapshotTable writes a row for each snapshot
+func WriteSnapshotTable(w io.Writer, snapshots []Snapshot) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tHOST\tSTATUS\tDEPLOYED\tRETRIEVED")
+	for _, snapshot := range snapshots {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n",
+			snapshot.Name,
+			snapshot.Host,
+			snapshot.Status,
+			snapshot.Deployed.Count(),
+			snapshot.Retrieved.Count(),
+		)
+	}
+	return tw.Flush()
+}
+
+// Rollback redeploys a snapshot to the site it was taken from
+func Rollback(auth *Authorization, snapshot *Snapshot) (results []NlxDeploymentResult, err error) {
+	if snapshot.Host != auth.Host {
+		err = fmt.Errorf("snapshot %v was taken from %v, not %v", snapshot.Name, snapshot.Host, auth.Host)
+		return
+	}
+
+	if snapshot.Retrieved.Count() == 0 {
+		err = fmt.Errorf("snapshot %v has no metadata to roll back to", snapshot.Name)
+		return
+	}
+
+	for _, metadataType := range GetMetadataTypeDirNames() {
+		names, _ := snapshot.New().GetFieldValueByName(metadataType)
+		for _, name := range names {
+			logging.Get().Warnf("%v %v did not exist before the deploy and will not be removed", metadataType, color.Yellow.Sprint(name))
+		}
+	}
+
+	var payload []byte
+	if payload, err = Archive(snapshot.MetadataDirectory(), nil); err != nil {
+		return
+	}
+
+	var plans NlxDynamicPlanMap
+	if _, plans, err = GetDeployPlan(auth, payload, nil); err != nil {
+		return
+	}
+
+	if _, results, err = ExecuteDeployPlan(auth, plans, snapshot.MetadataDirectory(), nil); err != nil {
+		snapshot.Error = err.Error()
+		_ = snapshot.Save()
+		return
+	}
+
+	err = snapshot.Finish(SNAPSHOT_STATUS_ROLLED_BACK, nil)
+	return
+}
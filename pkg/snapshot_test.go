@@ -0,0 +1,96 @@
+package pkg_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestSnapshotSaveAndList(t *testing.T) {
+	dir := t.TempDir()
+
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Metadata: pkg.NlxMetadata{Pages: []string{"Home", "New"}}},
+		pkg.DATA_PLAN_KEY:     pkg.NlxPlan{Metadata: pkg.NlxMetadata{DataSources: []string{"Orders"}}},
+	}
+
+	snapshot := pkg.NewSnapshot(dir, "https://example.skuidsite.com", plans)
+	assert.Equal(t, pkg.SNAPSHOT_STATUS_PENDING, snapshot.Status)
+	assert.Equal(t, pkg.NlxMetadata{Pages: []string{"Home", "New"}, DataSources: []string{"Orders"}}, snapshot.Deployed)
+	assert.Equal(t, filepath.Join(dir, ".skuid", "snapshots", snapshot.Name, "metadata"), snapshot.MetadataDirectory())
+
+	snapshot.Retrieved = pkg.NlxMetadata{Pages: []string{"Home"}, DataSources: []string{"Orders"}}
+	assert.Equal(t, pkg.NlxMetadata{Pages: []string{"New"}}, snapshot.New())
+	assert.NoError(t, snapshot.Save())
+
+	// a deploy that hasn't finished can't be rolled back to
+	_, err := pkg.LatestSnapshot(dir)
+	assert.Error(t, err)
+
+	assert.NoError(t, snapshot.Finish(pkg.SNAPSHOT_STATUS_DEPLOYED, nil))
+
+	loaded, err := pkg.LoadSnapshot(dir, snapshot.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.SNAPSHOT_STATUS_DEPLOYED, loaded.Status)
+	assert.Equal(t, snapshot.Retrieved, loaded.Retrieved)
+	assert.Equal(t, snapshot.MetadataDirectory(), loaded.MetadataDirectory())
+
+	latest, err := pkg.LatestSnapshot(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Name, latest.Name)
+
+	// unreadable snapshots are skipped
+	assert.NoError(t, os.MkdirAll(filepath.Join(pkg.SnapshotsDirectory(dir), "broken"), 0755))
+	snapshots, err := pkg.ListSnapshots(dir)
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+
+	var out bytes.Buffer
+	assert.NoError(t, pkg.WriteSnapshotTable(&out, snapshots))
+	assert.Regexp(t, snapshot.Name+`\s+https://example.skuidsite.com\s+deployed\s+3\s+2`, out.String())
+
+	_, err = pkg.LoadSnapshot(dir, "missing")
+	assert.Error(t, err)
+}
+
+func TestListSnapshotsEmpty(t *testing.T) {
+	snapshots, err := pkg.ListSnapshots(t.TempDir())
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestPlanPayloadRestrict(t *testing.T) {
+	plans := pkg.NlxPlanPayload{
+		MetadataService: &pkg.NlxPlan{
+			Host:     "pliny",
+			Since:    "2023-01-01",
+			Metadata: pkg.NlxMetadata{Pages: []string{"Home", "Other"}, Apps: []string{"Sales"}},
+		},
+		CloudDataService: &pkg.NlxPlan{
+			Host:     "warden",
+			Metadata: pkg.NlxMetadata{DataSources: []string{"Unused"}},
+		},
+	}
+
+	restricted := plans.Restrict(pkg.NlxMetadata{Pages: []string{"Home", "New"}, DataSources: []string{"Orders"}})
+	if assert.NotNil(t, restricted.MetadataService) {
+		assert.Equal(t, "pliny", restricted.MetadataService.Host)
+		assert.Equal(t, "", restricted.MetadataService.Since)
+		assert.Equal(t, pkg.NlxMetadata{Pages: []string{"Home"}}, restricted.MetadataService.Metadata)
+	}
+	assert.Nil(t, restricted.CloudDataService)
+
+	// the original plan is untouched
+	assert.Equal(t, "2023-01-01", plans.MetadataService.Since)
+}
+
+func TestRollbackWrongHost(t *testing.T) {
+	snapshot := pkg.NewSnapshot(t.TempDir(), "https://a.skuidsite.com", pkg.NlxDynamicPlanMap{})
+	_, err := pkg.Rollback(&pkg.Authorization{Host: "https://b.skuidsite.com"}, &snapshot)
+	assert.ErrorContains(t, err, "was taken from https://a.skuidsite.com")
+}
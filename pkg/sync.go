@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// syncDataSources tells pliny to sync its data sources' external_id field
+// with warden's ids
+func syncDataSources(auth *Authorization, plan NlxPlan) (err error) {
+	headers := GeneratePlanHeaders(auth, plan)
+	plan.Endpoint = deploySyncEndpoint
+	_, err = Request(
+		GenerateRoute(auth, plan),
+		http.MethodPost,
+		[]byte{},
+		headers,
+	)
+	return
+}
+
+// updatePermissionSets creates warden's data source permissions for the
+// permission sets, using the UUIDs that pliny assigned them. The plan is
+// warden's, from a deploy or retrieval plan.
+func updatePermissionSets(auth *Authorization, plan NlxPlan, permissionSets []PermissionSetResult) (err error) {
+	headers := GeneratePlanHeaders(auth, plan)
+	plan.Endpoint = updatePermissionSetsEndpoint
+	var payload []byte
+	if payload, err = json.Marshal(permissionSets); err != nil {
+		return
+	}
+	_, err = Request(
+		GenerateRoute(auth, plan),
+		http.MethodPost,
+		payload,
+		headers,
+	)
+	return
+}
+
+// SyncDataSources tells pliny to sync its data sources' external ids with
+// warden's, the same as the last stage of a deploy
+func SyncDataSources(auth *Authorization) error {
+	// an empty plan is a pliny request to the authorized host
+	return syncDataSources(auth, NlxPlan{})
+}
+
+// SyncPermissionSets retrieves the site's permission sets from pliny, with
+// their UUIDs, and sends them to warden, the same as the permission set
+// stage of a deploy. It returns the permission sets that were sent.
+func SyncPermissionSets(auth *Authorization) (permissionSets []PermissionSetResult, err error) {
+	var plans NlxPlanPayload
+	if _, plans, err = GetRetrievePlan(auth, nil); err != nil {
+		return
+	}
+	if plans.MetadataService == nil || plans.CloudDataService == nil {
+		err = fmt.Errorf("%v doesn't have both a Skuid NLX and a Skuid Cloud Data Service plan", auth.Host)
+		return
+	}
+
+	// only retrieve the permission sets
+	metadataPlan := *plans.MetadataService
+	metadataPlan.Metadata = NlxMetadata{PermissionSets: metadataPlan.Metadata.PermissionSets}
+	if len(metadataPlan.Metadata.PermissionSets) == 0 {
+		logging.Get().Infof("%v has no permission sets", auth.Host)
+		return
+	}
+
+	var results []NlxRetrievalResult
+	if _, results, err = ExecuteRetrieval(auth, NlxPlanPayload{MetadataService: &metadataPlan}); err != nil {
+		return
+	}
+	for _, result := range results {
+		var retrieved []PermissionSetResult
+		if retrieved, err = permissionSetsFromArchive(result.Data); err != nil {
+			return
+		}
+		permissionSets = append(permissionSets, retrieved...)
+	}
+	if len(permissionSets) == 0 {
+		return
+	}
+
+	err = updatePermissionSets(auth, *plans.CloudDataService, permissionSets)
+	return
+}
+
+// permissionSetFile is a retrieved permission set. Its keys are camel case,
+// unlike the permission sets in pliny's deploy response that warden expects.
+type permissionSetFile struct {
+	Id                    string      `json:"id"`
+	Name                  string      `json:"name"`
+	Description           string      `json:"description"`
+	AppId                 string      `json:"appId"`
+	AppName               string      `json:"appName"`
+	OrganizationId        string      `json:"organizationId"`
+	DatasourcePermissions interface{} `json:"dataSourcePermissions"`
+}
+
+// permissionSetsFromArchive reads the permission sets in a retrieved archive.
+// Every permission set needs the UUID that pliny assigned it, since that's
+// what warden's data source permissions refer to.
+func permissionSetsFromArchive(data []byte) (permissionSets []PermissionSetResult, err error) {
+	var reader *zip.Reader
+	if reader, err = zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+		return
+	}
+
+	for _, file := range reader.File {
+		if util.MetadataTypeFromArchivePath(file.Name) != "permissionsets" || !strings.HasSuffix(file.Name, ".json") {
+			continue
+		}
+
+		var contents io.ReadCloser
+		if contents, err = file.Open(); err != nil {
+			return
+		}
+		var permissionSet permissionSetFile
+		err = json.NewDecoder(contents).Decode(&permissionSet)
+		contents.Close()
+		if err != nil {
+			err = fmt.Errorf("unable to read %v: %w", file.Name, err)
+			return
+		}
+		if permissionSet.Id == "" {
+			err = fmt.Errorf("%v has no id", file.Name)
+			return
+		}
+		permissionSets = append(permissionSets, PermissionSetResult{
+			AppId:                 permissionSet.AppId,
+			AppName:               permissionSet.AppName,
+			Id:                    permissionSet.Id,
+			Name:                  permissionSet.Name,
+			Description:           permissionSet.Description,
+			OrganizationId:        permissionSet.OrganizationId,
+			DatasourcePermissions: permissionSet.DatasourcePermissions,
+		})
+	}
+	return
+}
@@ -0,0 +1,142 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+// a permission set as it's retrieved, with the id that pliny assigned it
+const retrievedPermissionSet = `{
+	"id": "7c1f3e52-58a4-4d0e-9a53-1f6cbb2e9d11",
+	"name": "Admin",
+	"description": "Full access to the Sales app",
+	"appId": "2d9b7a1e-3f1c-4b6a-8e2d-5a4c3b2a1f00",
+	"appName": "Sales",
+	"organizationId": "e4c9a8b7-6d5e-4f3a-2b1c-0d9e8f7a6b5c",
+	"appPermissions": {
+		"Sales": {
+			"isDefault": true
+		}
+	},
+	"dataSourcePermissions": {
+		"Orders": {
+			"dataSourceObjectPermissions": {
+				"Order": {
+					"createable": true,
+					"queryable": true
+				}
+			}
+		}
+	}
+}`
+
+func TestSyncPermissionSets(t *testing.T) {
+	for _, tc := range []struct {
+		description     string
+		permissionSets  map[string]string
+		planned         string
+		expectedSent    []pkg.PermissionSetResult
+		expectedUpdates []string
+		expectedError   string
+	}{
+		{
+			description:    "permission sets",
+			permissionSets: map[string]string{"permissionsets/Admin.json": retrievedPermissionSet},
+			planned:        `"permissionsets":["Admin"],"pages":["Home"]`,
+			expectedSent: []pkg.PermissionSetResult{
+				{
+					AppId:          "2d9b7a1e-3f1c-4b6a-8e2d-5a4c3b2a1f00",
+					AppName:        "Sales",
+					Id:             "7c1f3e52-58a4-4d0e-9a53-1f6cbb2e9d11",
+					Name:           "Admin",
+					Description:    "Full access to the Sales app",
+					OrganizationId: "e4c9a8b7-6d5e-4f3a-2b1c-0d9e8f7a6b5c",
+					DatasourcePermissions: map[string]interface{}{
+						"Orders": map[string]interface{}{
+							"dataSourceObjectPermissions": map[string]interface{}{
+								"Order": map[string]interface{}{"createable": true, "queryable": true},
+							},
+						},
+					},
+				},
+			},
+			// warden gets the same keys as pliny's deploy response has
+			expectedUpdates: []string{`[{"app_id":"2d9b7a1e-3f1c-4b6a-8e2d-5a4c3b2a1f00","app_name":"Sales","id":"7c1f3e52-58a4-4d0e-9a53-1f6cbb2e9d11","name":"Admin","description":"Full access to the Sales app","organization_id":"e4c9a8b7-6d5e-4f3a-2b1c-0d9e8f7a6b5c","dataSourcePermissions":{"Orders":{"dataSourceObjectPermissions":{"Order":{"createable":true,"queryable":true}}}}}]`},
+		},
+		{
+			description: "no permission sets",
+			planned:     `"pages":["Home"]`,
+		},
+		{
+			description:    "no id",
+			permissionSets: map[string]string{"permissionsets/Admin.json": `{"name":"Admin"}`},
+			planned:        `"permissionsets":["Admin"]`,
+			expectedError:  "permissionsets/Admin.json has no id",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			retrieved, err := pkg.Archive(writeTestSite(t, tc.permissionSets), nil)
+			assert.NoError(t, err)
+
+			var retrievals, updates []string
+			auth := testSite(t, func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				switch r.URL.Path {
+				case "/api/v2/metadata/retrieve/plan":
+					_, _ = fmt.Fprintf(w, `{
+						"skuidMetadataService":{"url":"/metadata/retrieve","type":"metadataService","metadata":{%v}},
+						"skuidCloudDataService":{"host":"https://%v","url":"/metadata/retrieve","type":"dataService","metadata":{"datasources":["Orders"]}}
+					}`, tc.planned, r.Host)
+				case "/api/v2/metadata/retrieve":
+					retrievals = append(retrievals, string(body))
+					_, _ = w.Write(retrieved)
+				case "/api/v2/metadata/update-permissionsets":
+					updates = append(updates, string(body))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			sent, err := pkg.SyncPermissionSets(auth)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedSent, sent)
+			assert.Equal(t, tc.expectedUpdates, updates)
+
+			// only the permission sets are retrieved
+			if tc.permissionSets == nil {
+				assert.Empty(t, retrievals)
+			} else if assert.Len(t, retrievals, 1) {
+				var request struct {
+					Metadata pkg.NlxMetadata `json:"metadata"`
+				}
+				assert.NoError(t, json.Unmarshal([]byte(retrievals[0]), &request))
+				assert.Equal(t, pkg.NlxMetadata{PermissionSets: []string{"Admin"}}, request.Metadata)
+			}
+		})
+	}
+}
+
+func TestSyncDataSources(t *testing.T) {
+	var requests []string
+	auth := testSite(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%v %v %s", r.Method, r.URL.Path, body))
+		if r.URL.Path != "/api/v2/metadata/deploy/sync" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	assert.NoError(t, pkg.SyncDataSources(auth))
+	assert.Equal(t, []string{"POST /api/v2/metadata/deploy/sync "}, requests)
+}
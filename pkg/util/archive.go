@@ -84,15 +84,24 @@ func UnzipArchive(sourceFileLocation, targetLocation string, fileCreator FileCre
 		// Sanitize all metadata .json files that aren't included as files on the site itself
 		if !strings.Contains(path, "files/") {
 			if filepath.Ext(path) == ".json" {
-				if fileReader, err = SanitizeZip(fileReader); err != nil {
+				if fileReader, err = SanitizeZipForType(fileReader, MetadataTypeFromArchivePath(file.Name)); err != nil {
 					logging.Get().Warnf("Error Sanitizing Zip: %v", err)
 					return
 				}
 			}
 		}
 
+		// Normalize page xml so that formatting changes between platform
+		// versions don't show up as differences
+		if filepath.Ext(path) == ".xml" && MetadataTypeFromArchivePath(file.Name) == "pages" {
+			if fileReader, err = SanitizeXml(fileReader); err != nil {
+				logging.Get().Warnf("Error Sanitizing XML: %v", err)
+				return
+			}
+		}
+
 		if fileAlreadyWritten {
-			if fileReader, err = CombineJSON(fileReader, existingFileReader, path); err != nil {
+			if fileReader, err = CombineJSON(fileReader, existingFileReader, path, MetadataTypeFromArchivePath(file.Name)); err != nil {
 				logging.Get().Warnf("Error Combining JSON: %v", err)
 				return
 			}
@@ -112,7 +121,23 @@ func UnzipArchive(sourceFileLocation, targetLocation string, fileCreator FileCre
 	return
 }
 
+// MetadataTypeFromArchivePath returns the top level directory of an archive
+// entry, which is the metadata type, i.e. pages/MyPage.json ---> pages
+func MetadataTypeFromArchivePath(name string) string {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "/")
+	if index := strings.Index(name, "/"); index >= 0 {
+		return name[:index]
+	}
+	return ""
+}
+
 func SanitizeZip(reader io.ReadCloser) (newReader io.ReadCloser, err error) {
+	return SanitizeZipForType(reader, "")
+}
+
+// SanitizeZipForType resorts the json with the key order policy for the metadata type
+// and removes the volatile fields for that type
+func SanitizeZipForType(reader io.ReadCloser, metadataType string) (newReader io.ReadCloser, err error) {
 	var b []byte
 	if b, err = io.ReadAll(reader); err != nil {
 		logging.Get().Warnf("unable to read all: %v", err)
@@ -120,7 +145,9 @@ func SanitizeZip(reader io.ReadCloser) (newReader io.ReadCloser, err error) {
 	}
 	defer reader.Close()
 
-	if b, err = ReSortJson(b); err != nil {
+	canonicalizer := NewJsonCanonicalizer(KeyOrder, JSON_INDENT)
+	canonicalizer.VolatileFields = VolatileFields
+	if b, err = canonicalizer.CanonicalizeBytes(b, metadataType); err != nil {
 		logging.Get().Warnf("unable to re-sort: %v", err)
 		return
 	}
@@ -0,0 +1,307 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// KeyOrderPolicy decides the order that the keys of a json object are
+// written in. Leading keys are written first, in the order they're listed,
+// and every other key follows alphabetically.
+type KeyOrderPolicy struct {
+	// Leading keys used for every metadata type without an override
+	Leading []string
+	// TypeLeading overrides Leading for a metadata type (e.g. "datasources")
+	TypeLeading map[string][]string
+}
+
+var (
+	// DefaultKeyOrderPolicy puts "name" first, then everything alphabetically
+	DefaultKeyOrderPolicy = KeyOrderPolicy{
+		Leading: []string{"name"},
+	}
+
+	// KeyOrder is the key order policy that retrieved json is written with
+	KeyOrder = DefaultKeyOrderPolicy
+)
+
+// SetKeyOrder replaces the leading keys for each metadata type in the
+// overrides. Types that aren't overridden keep their current keys, and an
+// empty list writes every key of a type alphabetically.
+func SetKeyOrder(overrides map[string][]string) {
+	typeLeading := make(map[string][]string, len(KeyOrder.TypeLeading)+len(overrides))
+	for metadataType, leading := range KeyOrder.TypeLeading {
+		typeLeading[metadataType] = leading
+	}
+	for metadataType, keys := range overrides {
+		leading := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if key = strings.TrimSpace(key); key != "" {
+				leading = append(leading, key)
+			}
+		}
+		typeLeading[strings.ToLower(metadataType)] = leading
+	}
+	KeyOrder = KeyOrderPolicy{Leading: KeyOrder.Leading, TypeLeading: typeLeading}
+}
+
+// LeadingFor returns the leading keys for a metadata type
+func (policy KeyOrderPolicy) LeadingFor(metadataType string) []string {
+	if leading, ok := policy.TypeLeading[metadataType]; ok {
+		return leading
+	}
+	return policy.Leading
+}
+
+// rank returns the position of a key in the leading keys, or the
+// number of leading keys if it isn't one of them
+func rank(leading []string, key string) int {
+	for i, l := range leading {
+		if l == key {
+			return i
+		}
+	}
+	return len(leading)
+}
+
+// JsonCanonicalizer rewrites json with a stable key order and indentation.
+// Numbers are kept as the literal text we received (json.Number) so large
+// ids and precise decimals are never round tripped through a float64.
+type JsonCanonicalizer struct {
+	Policy KeyOrderPolicy
+	// Indent is the string used for each level of indentation. An empty
+	// string writes compact json.
+	Indent string
+	// VolatileFields are paths removed from the json, by metadata type
+	VolatileFields map[string][]string
+}
+
+func NewJsonCanonicalizer(policy KeyOrderPolicy, indent string) *JsonCanonicalizer {
+	return &JsonCanonicalizer{
+		Policy: policy,
+		Indent: indent,
+	}
+}
+
+// jsonNode is a decoded json value. Objects only keep the last value
+// for a duplicated key, which is the same as unmarshalling into a map.
+type jsonNode struct {
+	delim   json.Delim // '{' or '[' for objects and arrays, 0 for scalars
+	raw     []byte     // encoded scalar
+	members []jsonMember
+	items   []*jsonNode
+}
+
+type jsonMember struct {
+	key   string
+	rank  int
+	value *jsonNode
+}
+
+// Canonicalize reads a single json value from the reader and writes the
+// canonical form for the metadata type to the writer
+func (c *JsonCanonicalizer) Canonicalize(r io.Reader, w io.Writer, metadataType string) (err error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var root *jsonNode
+	if root, err = readJsonNode(decoder); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+
+	// anything after the top level value is invalid
+	if _, err = decoder.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("invalid character after top-level value")
+		}
+		return
+	}
+
+	for _, path := range c.VolatileFields[metadataType] {
+		root.remove(strings.Split(path, "."))
+	}
+
+	buffered := bufio.NewWriter(w)
+	if err = c.writeJsonNode(buffered, root, metadataType, 0); err != nil {
+		return
+	}
+	return buffered.Flush()
+}
+
+// CanonicalizeBytes is Canonicalize for a byte slice
+func (c *JsonCanonicalizer) CanonicalizeBytes(data []byte, metadataType string) (canonical []byte, err error) {
+	var buffer bytes.Buffer
+	if err = c.Canonicalize(bytes.NewReader(data), &buffer, metadataType); err != nil {
+		return
+	}
+	canonical = buffer.Bytes()
+	return
+}
+
+func readJsonNode(decoder *json.Decoder) (node *jsonNode, err error) {
+	var token json.Token
+	if token, err = decoder.Token(); err != nil {
+		return
+	}
+
+	node = &jsonNode{}
+	switch value := token.(type) {
+	case json.Delim:
+		node.delim = value
+		switch value {
+		case '{':
+			for decoder.More() {
+				if token, err = decoder.Token(); err != nil {
+					return
+				}
+				key, _ := token.(string)
+				var child *jsonNode
+				if child, err = readJsonNode(decoder); err != nil {
+					return
+				}
+				node.members = append(node.members, jsonMember{key: key, value: child})
+			}
+		case '[':
+			for decoder.More() {
+				var child *jsonNode
+				if child, err = readJsonNode(decoder); err != nil {
+					return
+				}
+				node.items = append(node.items, child)
+			}
+		}
+		// consume the closing delimiter
+		_, err = decoder.Token()
+	case json.Number:
+		node.raw = []byte(value.String())
+	default:
+		// strings, booleans and null are re-encoded the same way
+		// encoding/json would write them
+		node.raw, err = json.Marshal(value)
+	}
+
+	return
+}
+
+// remove deletes the members at the path. A "*" segment matches every
+// member or array item, and arrays are searched without needing a "*".
+func (node *jsonNode) remove(path []string) {
+	if len(path) == 0 {
+		return
+	}
+	switch node.delim {
+	case '[':
+		rest := path
+		if path[0] == "*" {
+			rest = path[1:]
+		}
+		for _, item := range node.items {
+			item.remove(rest)
+		}
+	case '{':
+		kept := node.members[:0]
+		for _, member := range node.members {
+			if member.key == path[0] || path[0] == "*" {
+				if len(path) == 1 {
+					continue
+				}
+				member.value.remove(path[1:])
+			}
+			kept = append(kept, member)
+		}
+		node.members = kept
+	}
+}
+
+// sortMembers orders the members of an object with the leading keys first
+// and everything else alphabetically, dropping all but the last value of
+// any duplicated key
+func (node *jsonNode) sortMembers(leading []string) {
+	for i := range node.members {
+		node.members[i].rank = rank(leading, node.members[i].key)
+	}
+	sort.SliceStable(node.members, func(i, j int) bool {
+		a, b := node.members[i], node.members[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.key < b.key
+	})
+	deduped := node.members[:0]
+	for _, member := range node.members {
+		if last := len(deduped) - 1; last >= 0 && deduped[last].key == member.key {
+			deduped[last] = member
+			continue
+		}
+		deduped = append(deduped, member)
+	}
+	node.members = deduped
+}
+
+func (c *JsonCanonicalizer) writeNewline(w *bufio.Writer, depth int) {
+	if c.Indent == "" {
+		return
+	}
+	w.WriteByte('\n')
+	w.WriteString(strings.Repeat(c.Indent, depth))
+}
+
+func (c *JsonCanonicalizer) writeJsonNode(w *bufio.Writer, node *jsonNode, metadataType string, depth int) (err error) {
+	switch node.delim {
+	case '{':
+		if len(node.members) == 0 {
+			_, err = w.WriteString("{}")
+			return
+		}
+		node.sortMembers(c.Policy.LeadingFor(metadataType))
+		w.WriteByte('{')
+		for i, member := range node.members {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			c.writeNewline(w, depth+1)
+			var encodedKey []byte
+			if encodedKey, err = json.Marshal(member.key); err != nil {
+				return
+			}
+			w.Write(encodedKey)
+			w.WriteByte(':')
+			if c.Indent != "" {
+				w.WriteByte(' ')
+			}
+			if err = c.writeJsonNode(w, member.value, metadataType, depth+1); err != nil {
+				return
+			}
+		}
+		c.writeNewline(w, depth)
+		_, err = w.WriteString("}")
+	case '[':
+		if len(node.items) == 0 {
+			_, err = w.WriteString("[]")
+			return
+		}
+		w.WriteByte('[')
+		for i, item := range node.items {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			c.writeNewline(w, depth+1)
+			if err = c.writeJsonNode(w, item, metadataType, depth+1); err != nil {
+				return
+			}
+		}
+		c.writeNewline(w, depth)
+		_, err = w.WriteString("]")
+	default:
+		_, err = w.Write(node.raw)
+	}
+	return
+}
@@ -0,0 +1,194 @@
+package util_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestJsonCanonicalizer(t *testing.T) {
+	policy := util.KeyOrderPolicy{
+		Leading: []string{"name"},
+		TypeLeading: map[string][]string{
+			"datasources": {"type", "name"},
+		},
+	}
+
+	for _, tc := range []struct {
+		description  string
+		metadataType string
+		given        string
+		expected     string
+		expectedErr  bool
+	}{
+		{
+			description: "large ids and precise decimals are preserved",
+			given:       `{"id":12345678901234567890,"price":0.10000000000000000001,"exp":1e3}`,
+			expected:    `{"exp":1e3,"id":12345678901234567890,"price":0.10000000000000000001}`,
+		},
+		{
+			description: "name first inside arrays",
+			given:       `{"fields":[{"type":"text","name":"a"},{"name":"b","label":"B"}],"name":"x"}`,
+			expected:    `{"name":"x","fields":[{"name":"a","type":"text"},{"name":"b","label":"B"}]}`,
+		},
+		{
+			description:  "per type override",
+			metadataType: "datasources",
+			given:        `{"a":1,"name":"x","type":"y"}`,
+			expected:     `{"type":"y","name":"x","a":1}`,
+		},
+		{
+			description:  "other types use the default",
+			metadataType: "pages",
+			given:        `{"a":1,"name":"x","type":"y"}`,
+			expected:     `{"name":"x","a":1,"type":"y"}`,
+		},
+		{
+			description: "placeholder text in content is untouched",
+			given:       `{"b":"\"%NAME%\"","name":"%NAME%"}`,
+			expected:    `{"name":"%NAME%","b":"\"%NAME%\""}`,
+		},
+		{
+			description: "duplicate keys keep the last value",
+			given:       `{"a":1,"a":2}`,
+			expected:    `{"a":2}`,
+		},
+		{
+			description: "scalars, empty containers and escaping",
+			given:       `{"t":true,"f":false,"n":null,"o":{},"l":[],"s":"<b>&</b>é"}`,
+			expected:    `{"f":false,"l":[],"n":null,"o":{},"s":"\u003cb\u003e\u0026\u003c/b\u003eé","t":true}`,
+		},
+		{
+			description: "top level array",
+			given:       `[{"z":1,"name":"n"}]`,
+			expected:    `[{"name":"n","z":1}]`,
+		},
+		{
+			description: "trailing data",
+			given:       `{"a":1} {"b":2}`,
+			expectedErr: true,
+		},
+		{
+			description: "empty",
+			given:       ``,
+			expectedErr: true,
+		},
+		{
+			description: "invalid",
+			given:       `{"a":}`,
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			actual, err := util.NewJsonCanonicalizer(policy, "").CanonicalizeBytes([]byte(tc.given), tc.metadataType)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
+
+func TestJsonCanonicalizerIndent(t *testing.T) {
+	given := `{"b":[1,{"c":2,"name":"n"}],"name":"x","e":{}}`
+	actual, err := util.NewJsonCanonicalizer(util.DefaultKeyOrderPolicy, "\t").CanonicalizeBytes([]byte(given), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n\t\"name\": \"x\",\n\t\"b\": [\n\t\t1,\n\t\t{\n\t\t\t\"name\": \"n\",\n\t\t\t\"c\": 2\n\t\t}\n\t],\n\t\"e\": {}\n}", string(actual))
+
+	// without arrays of objects this matches encoding/json's indentation
+	simple := `{"name":"x","a":{"b":[1,2]},"c":"d"}`
+	expected := new(bytes.Buffer)
+	assert.NoError(t, json.Indent(expected, []byte(simple), "", "\t"))
+	actual, err = util.ReSortJson([]byte(simple))
+	assert.NoError(t, err)
+	assert.Equal(t, expected.String(), string(actual))
+}
+
+// legacyReSortJson is the map based implementation that the canonicalizer
+// replaced, kept here to benchmark against
+func legacyReSortJson(data []byte) (replaced []byte, err error) {
+	const placeholder = `%NAME%`
+	var insert func(in map[string]interface{}) map[string]interface{}
+	insert = func(in map[string]interface{}) map[string]interface{} {
+		for k, v := range in {
+			if k == "name" {
+				delete(in, k)
+				k = placeholder
+				in[k] = v
+			}
+			if m, anotherMap := v.(map[string]interface{}); anotherMap {
+				in[k] = insert(m)
+			}
+		}
+		return in
+	}
+
+	var unsorted map[string]interface{}
+	if err = json.Unmarshal(data, &unsorted); err != nil {
+		return
+	}
+	insert(unsorted)
+	var sorted []byte
+	if sorted, err = json.MarshalIndent(unsorted, "", "\t"); err != nil {
+		return
+	}
+	replaced = []byte(strings.ReplaceAll(string(sorted), fmt.Sprintf(`"%v"`, placeholder), `"name"`))
+	return
+}
+
+func benchmarkJson() []byte {
+	fields := make([]string, 0, 200)
+	for i := 0; i < 200; i++ {
+		fields = append(fields, fmt.Sprintf(`{"type":"TEXT","label":"Field %d","id":%d,"name":"field%d","options":{"z":true,"name":"o%d","a":[1,2,3]}}`, i, 900719925474099100+i, i, i))
+	}
+	return []byte(fmt.Sprintf(`{"type":"custom","fields":[%v],"name":"benchmark","config":{"url":"https://example.com","timeout":30.5}}`, strings.Join(fields, ",")))
+}
+
+func BenchmarkLegacyReSortJson(b *testing.B) {
+	data := benchmarkJson()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := legacyReSortJson(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJsonCanonicalizer(b *testing.B) {
+	data := benchmarkJson()
+	canonicalizer := util.NewJsonCanonicalizer(util.DefaultKeyOrderPolicy, "\t")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := canonicalizer.CanonicalizeBytes(data, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSetKeyOrder(t *testing.T) {
+	defer func() { util.KeyOrder = util.DefaultKeyOrderPolicy }()
+	normalize := func(path, given string) string {
+		return string(util.NormalizeMetadataFile(path, []byte(given)))
+	}
+
+	util.SetKeyOrder(map[string][]string{
+		"DataSources": {" type ", "", "name"},
+		"pages":       {},
+	})
+	assert.Equal(t, "{\n\t\"type\": \"y\",\n\t\"name\": \"x\",\n\t\"a\": 1\n}", normalize("datasources/x.json", `{"a":1,"name":"x","type":"y"}`))
+	assert.Equal(t, "{\n\t\"a\": 1,\n\t\"name\": \"x\"\n}", normalize("pages/x.json", `{"name":"x","a":1}`))
+	assert.Equal(t, "{\n\t\"name\": \"x\",\n\t\"a\": 1\n}", normalize("apps/x.json", `{"a":1,"name":"x"}`))
+
+	// the default is left as it was
+	assert.Empty(t, util.DefaultKeyOrderPolicy.TypeLeading)
+}
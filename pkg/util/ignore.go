@@ -0,0 +1,204 @@
+package util
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SKUIDIGNORE_FILE lists, in gitignore syntax, the files in a metadata
+// directory that are never deployed
+const SKUIDIGNORE_FILE = ".skuidignore"
+
+type ignorePattern struct {
+	expression *regexp.Regexp
+	negate     bool
+	dirOnly    bool
+}
+
+// IgnoreMatcher matches relative, slash separated paths against the patterns
+// of an ignore file. A nil matcher ignores nothing.
+type IgnoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// ParseIgnore reads gitignore syntax patterns
+func ParseIgnore(r io.Reader) (matcher *IgnoreMatcher, err error) {
+	matcher = &IgnoreMatcher{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if pattern, ok := compileIgnorePattern(scanner.Text()); ok {
+			matcher.patterns = append(matcher.patterns, pattern)
+		}
+	}
+	err = scanner.Err()
+	return
+}
+
+// LoadIgnore reads the .skuidignore at the root of the directory. A missing
+// file ignores nothing.
+func LoadIgnore(directory string) (matcher *IgnoreMatcher, err error) {
+	var file *os.File
+	if file, err = os.Open(filepath.Join(directory, SKUIDIGNORE_FILE)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return
+	}
+	defer file.Close()
+	return ParseIgnore(file)
+}
+
+func compileIgnorePattern(line string) (pattern ignorePattern, ok bool) {
+	// trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return
+	}
+
+	// a slash anywhere but the end anchors the pattern to the root,
+	// otherwise it matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expression strings.Builder
+	expression.WriteString("^")
+	if !anchored {
+		expression.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/") && (i == 0 || line[i-1] == '/'):
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "/**") && i+3 == len(line):
+			expression.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case c == '*':
+			expression.WriteString("[^/]*")
+		case c == '?':
+			expression.WriteString("[^/]")
+		case c == '\\' && i+1 < len(line):
+			i++
+			expression.WriteString(regexp.QuoteMeta(string(line[i])))
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				expression.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expression.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expression.WriteString("$")
+
+	var err error
+	if pattern.expression, err = regexp.Compile(expression.String()); err != nil {
+		return
+	}
+	ok = true
+	return
+}
+
+// match returns whether the last pattern matching the path ignores it
+func (matcher *IgnoreMatcher) match(path string, isDir bool) (ignored bool) {
+	for _, pattern := range matcher.patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		if pattern.expression.MatchString(path) {
+			ignored = !pattern.negate
+		}
+	}
+	return
+}
+
+// Ignored returns true if the path, relative to the directory the ignore file
+// is in, is ignored. Like git, nothing inside an ignored directory can be
+// un-ignored.
+func (matcher *IgnoreMatcher) Ignored(relativePath string, isDir bool) bool {
+	if matcher == nil || len(matcher.patterns) == 0 {
+		return false
+	}
+
+	path := strings.Trim(filepath.ToSlash(FromWindowsPath(relativePath)), "/")
+	path = strings.TrimPrefix(path, "./")
+	if path == "" || path == "." {
+		return false
+	}
+
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if matcher.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return matcher.match(path, isDir)
+}
+
+// IgnoredFiles lists the files in the directory that its .skuidignore ignores,
+// relative to the directory. Hidden files are always ignored and aren't listed.
+func IgnoredFiles(directory string) (ignored []string, err error) {
+	var matcher *IgnoreMatcher
+	if matcher, err = LoadIgnore(directory); err != nil || matcher == nil {
+		return
+	}
+
+	err = filepath.Walk(directory, func(filePath string, fileInfo os.FileInfo, e error) (err error) {
+		if e != nil {
+			return e
+		}
+
+		var relativePath string
+		if relativePath, err = filepath.Rel(directory, filePath); err != nil {
+			return
+		}
+		if relativePath == "." {
+			return
+		}
+		if strings.HasPrefix(relativePath, ".") {
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return
+		}
+
+		if !fileInfo.IsDir() && matcher.Ignored(relativePath, false) {
+			ignored = append(ignored, filepath.ToSlash(relativePath))
+		}
+		return
+	})
+	return
+}
@@ -0,0 +1,93 @@
+package util_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	matcher, err := util.ParseIgnore(strings.NewReader(strings.Join([]string{
+		"# comments and blank lines are skipped",
+		"",
+		"*.md",
+		"!pages/KEEP.md",
+		"/build",
+		"fixtures/",
+		"docs/**/*.png",
+		"**/tmp",
+		"*.sw[op]",
+		"\\#notes",
+		"trailing   ",
+	}, "\n")))
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"README.md", false, true},
+		{"pages/README.md", false, true},
+		{"pages/KEEP.md", false, false},
+		{"pages/Home.json", false, false},
+		{"build", true, true},
+		{"build/output.zip", false, true},
+		// anchored to the root
+		{"pages/build", false, false},
+		// directories only, at any depth
+		{"fixtures", false, false},
+		{"pages/fixtures/Home.json", false, true},
+		{"docs/a/b/image.png", false, true},
+		{"docs/image.png", false, true},
+		{"pages/image.png", false, false},
+		{"a/b/tmp/file", false, true},
+		{"pages/.Home.json.swp", false, true},
+		{"pages/Home.json.swx", false, false},
+		{"#notes", false, true},
+		{"trailing", false, true},
+		{`pages\README.md`, false, true},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			assert.Equal(t, tc.expected, matcher.Ignored(tc.path, tc.isDir))
+		})
+	}
+
+	// nothing inside an ignored directory can be un-ignored
+	matcher, err = util.ParseIgnore(strings.NewReader("fixtures/\n!fixtures/keep.json"))
+	assert.NoError(t, err)
+	assert.True(t, matcher.Ignored("fixtures/keep.json", false))
+
+	// a nil matcher ignores nothing
+	var none *util.IgnoreMatcher
+	assert.False(t, none.Ignored("README.md", false))
+}
+
+func TestIgnoredFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		".skuidignore":          "*.md\nfixtures/\n",
+		"README.md":             "docs",
+		"pages/Home.json":       "{}",
+		"pages/fixtures/a.json": "{}",
+		".git/README.md":        "hidden",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(body), 0644))
+	}
+
+	ignored, err := util.IgnoredFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"README.md", "pages/fixtures/a.json"}, ignored)
+
+	// no ignore file
+	ignored, err = util.IgnoredFiles(t.TempDir())
+	assert.NoError(t, err)
+	assert.Empty(t, ignored)
+}
@@ -12,7 +12,9 @@ import (
 	"github.com/skuid/skuid-cli/pkg/logging"
 )
 
-func CombineJSON(readCloser io.ReadCloser, fileReader FileReader, path string) (newReadCloser io.ReadCloser, err error) {
+// CombineJSON merges the json into the file already written at the path, and
+// sorts the result with the key order for the file's metadata type
+func CombineJSON(readCloser io.ReadCloser, fileReader FileReader, path, metadataType string) (newReadCloser io.ReadCloser, err error) {
 	fields := logrus.Fields{
 		"function": "CombineJSON",
 	}
@@ -37,11 +39,11 @@ func CombineJSON(readCloser io.ReadCloser, fileReader FileReader, path string) (
 		return
 	}
 
-	// sort all of the keys in the json. custom sort logic.
-	// this puts "name" first, then everything alphanumerically
-	sorted, err := ReSortJsonIndent(combined, true)
+	// sort all of the keys in the json the same way as the type's files
+	// that only one service wrote
+	sorted, err := ReSortJsonForType(combined, metadataType, true)
 	if err != nil {
-		logging.Get().Warnf("ReSortJsonIndent: %v", err)
+		logging.Get().Warnf("ReSortJsonForType: %v", err)
 		return
 	}
 
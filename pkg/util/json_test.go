@@ -3,6 +3,7 @@ package util_test
 import (
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -89,7 +90,7 @@ func TestCombineJSON(t *testing.T) {
 		},
 	} {
 		t.Run(tc.description, func(t *testing.T) {
-			_, err := util.CombineJSON(tc.readcloser, tc.reader, tc.path)
+			_, err := util.CombineJSON(tc.readcloser, tc.reader, tc.path, "")
 			if tc.expectedError != nil {
 				assert.Error(t, err)
 			} else {
@@ -98,3 +99,18 @@ func TestCombineJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestCombineJSONKeyOrder(t *testing.T) {
+	util.SetKeyOrder(map[string][]string{"datasources": {"type", "name"}})
+	defer func() { util.KeyOrder = util.DefaultKeyOrderPolicy }()
+
+	// a data source written by both services keeps its type's key order
+	existing := func(string) ([]byte, error) {
+		return []byte("{\n\t\"type\": \"rest\",\n\t\"name\": \"Orders\"\n}"), nil
+	}
+	combined, err := util.CombineJSON(io.NopCloser(strings.NewReader(`{"url":"https://api","name":"Orders"}`)), existing, "datasources/Orders.json", "datasources")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(combined)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n\t\"type\": \"rest\",\n\t\"name\": \"Orders\",\n\t\"url\": \"https://api\"\n}", string(data))
+}
@@ -0,0 +1,184 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const (
+	JSON_CHANGE_ADDED    = "added"
+	JSON_CHANGE_REMOVED  = "removed"
+	JSON_CHANGE_MODIFIED = "modified"
+)
+
+// JsonChange is a single structural difference between two json documents
+type JsonChange struct {
+	Path   string      `json:"path"`
+	Change string      `json:"change"`
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+}
+
+func (change JsonChange) String() string {
+	display := func(value interface{}) string {
+		b, _ := json.Marshal(value)
+		s := string(b)
+		if len(s) > 80 {
+			s = s[:77] + "..."
+		}
+		return s
+	}
+	switch change.Change {
+	case JSON_CHANGE_ADDED:
+		return fmt.Sprintf("+ %v: %v", change.Path, display(change.To))
+	case JSON_CHANGE_REMOVED:
+		return fmt.Sprintf("- %v: %v", change.Path, display(change.From))
+	default:
+		return fmt.Sprintf("~ %v: %v => %v", change.Path, display(change.From), display(change.To))
+	}
+}
+
+func decodeJsonValue(data []byte) (value interface{}, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&value)
+	return
+}
+
+// DiffJson compares two json documents structurally, ignoring key order
+// and whitespace. Arrays of objects that all have a unique "name" are
+// matched up by name, with a change to the order of the names on both sides
+// as a modification of the array, and everything else by position.
+func DiffJson(from, to []byte) (changes []JsonChange, err error) {
+	var fromValue, toValue interface{}
+	if fromValue, err = decodeJsonValue(from); err != nil {
+		return
+	}
+	if toValue, err = decodeJsonValue(to); err != nil {
+		return
+	}
+	changes = diffJsonValues("", fromValue, toValue, changes)
+	return
+}
+
+func joinJsonPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// namedItems returns the array items by name, if every item is an object
+// with a unique string name
+func namedItems(items []interface{}) (named map[string]interface{}, order []string, ok bool) {
+	named = make(map[string]interface{}, len(items))
+	for _, item := range items {
+		object, isObject := item.(map[string]interface{})
+		if !isObject {
+			return nil, nil, false
+		}
+		name, isString := object["name"].(string)
+		if !isString {
+			return nil, nil, false
+		}
+		if _, duplicate := named[name]; duplicate {
+			return nil, nil, false
+		}
+		named[name] = item
+		order = append(order, name)
+	}
+	return named, order, true
+}
+
+func diffJsonValues(path string, from, to interface{}, changes []JsonChange) []JsonChange {
+	switch fromValue := from.(type) {
+	case map[string]interface{}:
+		toValue, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(fromValue)+len(toValue))
+		for key := range fromValue {
+			keys = append(keys, key)
+		}
+		for key := range toValue {
+			if _, inFrom := fromValue[key]; !inFrom {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			changes = diffJsonMember(joinJsonPath(path, key), fromValue, toValue, key, changes)
+		}
+		return changes
+	case []interface{}:
+		toValue, ok := to.([]interface{})
+		if !ok {
+			break
+		}
+		fromNamed, fromOrder, fromOk := namedItems(fromValue)
+		toNamed, toOrder, toOk := namedItems(toValue)
+		if fromOk && toOk && len(fromValue) > 0 && len(toValue) > 0 {
+			// items only on one side are added or removed, so only the
+			// order of the items on both sides can change
+			var fromCommon, toCommon []string
+			for _, name := range fromOrder {
+				if _, inTo := toNamed[name]; inTo {
+					fromCommon = append(fromCommon, name)
+				}
+			}
+			for _, name := range toOrder {
+				if _, inFrom := fromNamed[name]; inFrom {
+					toCommon = append(toCommon, name)
+				}
+			}
+			if !reflect.DeepEqual(fromCommon, toCommon) {
+				changes = append(changes, JsonChange{Path: path, Change: JSON_CHANGE_MODIFIED, From: fromCommon, To: toCommon})
+			}
+
+			names := append([]string{}, fromOrder...)
+			for _, name := range toOrder {
+				if _, inFrom := fromNamed[name]; !inFrom {
+					names = append(names, name)
+				}
+			}
+			for _, name := range names {
+				changes = diffJsonMember(fmt.Sprintf("%v[name=%v]", path, name), fromNamed, toNamed, name, changes)
+			}
+			return changes
+		}
+		for i := 0; i < len(fromValue) || i < len(toValue); i++ {
+			itemPath := fmt.Sprintf("%v[%v]", path, i)
+			switch {
+			case i >= len(toValue):
+				changes = append(changes, JsonChange{Path: itemPath, Change: JSON_CHANGE_REMOVED, From: fromValue[i]})
+			case i >= len(fromValue):
+				changes = append(changes, JsonChange{Path: itemPath, Change: JSON_CHANGE_ADDED, To: toValue[i]})
+			default:
+				changes = diffJsonValues(itemPath, fromValue[i], toValue[i], changes)
+			}
+		}
+		return changes
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		changes = append(changes, JsonChange{Path: path, Change: JSON_CHANGE_MODIFIED, From: from, To: to})
+	}
+	return changes
+}
+
+func diffJsonMember(path string, from, to map[string]interface{}, key string, changes []JsonChange) []JsonChange {
+	fromValue, inFrom := from[key]
+	toValue, inTo := to[key]
+	switch {
+	case !inTo:
+		return append(changes, JsonChange{Path: path, Change: JSON_CHANGE_REMOVED, From: fromValue})
+	case !inFrom:
+		return append(changes, JsonChange{Path: path, Change: JSON_CHANGE_ADDED, To: toValue})
+	default:
+		return diffJsonValues(path, fromValue, toValue, changes)
+	}
+}
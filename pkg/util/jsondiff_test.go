@@ -0,0 +1,115 @@
+package util_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestDiffJson(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		from        string
+		to          string
+		expected    []string
+	}{
+		{
+			description: "key order and whitespace",
+			from:        `{"name":"a","x":1,"y":{"z":true}}`,
+			to:          "{\n\t\"y\": {\"z\": true},\n\t\"x\": 1,\n\t\"name\": \"a\"\n}",
+		},
+		{
+			description: "nested changes",
+			from:        `{"name":"a","config":{"url":"https://a","timeout":10}}`,
+			to:          `{"name":"a","config":{"url":"https://b","retries":3}}`,
+			expected: []string{
+				`+ config.retries: 3`,
+				`- config.timeout: 10`,
+				`~ config.url: "https://a" => "https://b"`,
+			},
+		},
+		{
+			description: "named items are matched by name",
+			from:        `{"fields":[{"name":"a","type":"text"},{"name":"b","type":"text"}]}`,
+			to:          `{"fields":[{"name":"c","type":"text"},{"name":"a","type":"number"}]}`,
+			expected: []string{
+				`~ fields[name=a].type: "text" => "number"`,
+				`- fields[name=b]: {"name":"b","type":"text"}`,
+				`+ fields[name=c]: {"name":"c","type":"text"}`,
+			},
+		},
+		{
+			description: "named items that moved",
+			from:        `{"fields":[{"name":"a","type":"text"},{"name":"b","type":"text"},{"name":"c","type":"text"}]}`,
+			to:          `{"fields":[{"name":"d","type":"text"},{"name":"c","type":"text"},{"name":"a","type":"text"}]}`,
+			expected: []string{
+				`~ fields: ["a","c"] => ["c","a"]`,
+				`- fields[name=b]: {"name":"b","type":"text"}`,
+				`+ fields[name=d]: {"name":"d","type":"text"}`,
+			},
+		},
+		{
+			description: "named items that only shifted",
+			from:        `{"fields":[{"name":"a"},{"name":"b"}]}`,
+			to:          `{"fields":[{"name":"c"},{"name":"a"},{"name":"b"}]}`,
+			expected: []string{
+				`+ fields[name=c]: {"name":"c"}`,
+			},
+		},
+		{
+			description: "other items are matched by position",
+			from:        `{"values":[1,2,3]}`,
+			to:          `{"values":[1,4]}`,
+			expected: []string{
+				`~ values[1]: 2 => 4`,
+				`- values[2]: 3`,
+			},
+		},
+		{
+			description: "numbers keep their precision",
+			from:        `{"id":12345678901234567890}`,
+			to:          `{"id":12345678901234567891}`,
+			expected: []string{
+				`~ id: 12345678901234567890 => 12345678901234567891`,
+			},
+		},
+		{
+			description: "type changes",
+			from:        `{"value":{"a":1}}`,
+			to:          `{"value":[1]}`,
+			expected: []string{
+				`~ value: {"a":1} => [1]`,
+			},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			changes, err := util.DiffJson([]byte(tc.from), []byte(tc.to))
+			assert.NoError(t, err)
+			var actual []string
+			for _, change := range changes {
+				actual = append(actual, change.String())
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestDiffJsonInvalid(t *testing.T) {
+	_, err := util.DiffJson([]byte(`{"name":`), []byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestJsonChangeStringTruncates(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	change := util.JsonChange{Path: "x", Change: util.JSON_CHANGE_ADDED, To: json.Number("1")}
+	assert.Equal(t, "+ x: 1", change.String())
+
+	change = util.JsonChange{Path: "x", Change: util.JSON_CHANGE_REMOVED, From: long}
+	s := change.String()
+	assert.Len(t, s, len("- x: ")+80)
+	assert.Contains(t, s, "...")
+}
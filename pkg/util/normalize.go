@@ -0,0 +1,33 @@
+package util
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// NormalizeMetadataFile applies the same formatting to a metadata file that
+// UnzipArchive applies on retrieve, so local files can be compared with
+// retrieved ones. Files that can't be normalized are returned unchanged.
+func NormalizeMetadataFile(relativePath string, data []byte) []byte {
+	metadataType := MetadataTypeFromArchivePath(relativePath)
+	if metadataType == "files" {
+		return data
+	}
+
+	switch strings.ToLower(filepath.Ext(relativePath)) {
+	case ".json":
+		canonicalizer := NewJsonCanonicalizer(KeyOrder, JSON_INDENT)
+		canonicalizer.VolatileFields = VolatileFields
+		if normalized, err := canonicalizer.CanonicalizeBytes(data, metadataType); err == nil {
+			return normalized
+		}
+	case ".xml":
+		if metadataType == "pages" {
+			if normalized, err := NormalizeXml(data); err == nil {
+				return normalized
+			}
+		}
+	}
+
+	return data
+}
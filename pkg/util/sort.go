@@ -1,49 +1,13 @@
 package util
 
 import (
-	"encoding/json"
-	"fmt"
-	"strings"
-
 	"github.com/skuid/skuid-cli/pkg/logging"
 )
 
-// hopefully this doesn't mess with anything lol
-// might want to use foreign keys or something. if a
-// page has the word "%NAME%" in it, this might interfere.
 const (
-	PLACEHOLDER = `%NAME%`
+	JSON_INDENT = "\t"
 )
 
-// InsertNamePlaceholders replaces the thing that we want to go first (name) with
-// a placeholder that will show up alphabetically first (%NAME%). when golang
-// marshals maps into json, it sorts the keys. so we're going to piggyback off
-// that logic and then do a replacement on the string
-func InsertNamePlaceholders(in map[string]interface{}) map[string]interface{} {
-	for k, v := range in {
-		if k == "name" {
-			delete(in, k)
-			k = fmt.Sprintf("%v", PLACEHOLDER)
-			in[k] = v
-		}
-		if m, anotherMap := v.(map[string]interface{}); anotherMap {
-			in[k] = InsertNamePlaceholders(m)
-		}
-	}
-	return in
-}
-
-// we want to replace all of the placeholders with the actual
-// name string and return the bytes
-func ReplaceNamePlaceholders(in []byte) []byte {
-	return []byte(
-		strings.ReplaceAll(
-			string(in),
-			fmt.Sprintf(`"%v"`, PLACEHOLDER),
-			`"name"`),
-	)
-}
-
 func ReSortJson(data []byte) (replaced []byte, err error) {
 	return ReSortJsonIndent(data, true)
 }
@@ -51,30 +15,20 @@ func ReSortJson(data []byte) (replaced []byte, err error) {
 // this takes marshalled json in bytes and resorts it recursively the way
 // we want it, with "name" field first.
 func ReSortJsonIndent(data []byte, indent bool) (replaced []byte, err error) {
-	var unsorted map[string]interface{}
-	err = json.Unmarshal(data, &unsorted)
-	if err != nil {
-		logging.Get().Tracef("Error Unmarshalling into map[string]interface{}: %v", string(data))
-		return
-	}
-
-	// insert the name placeholders
-	InsertNamePlaceholders(unsorted)
+	return ReSortJsonForType(data, "", indent)
+}
 
-	// marshal, which uses the native ordering
-	// (will do alphanumerically)
-	var sorted []byte
+// ReSortJsonForType resorts the json using the KeyOrder policy for
+// the metadata type (the top level directory name, e.g. "datasources")
+func ReSortJsonForType(data []byte, metadataType string, indent bool) (replaced []byte, err error) {
+	var indentString string
 	if indent {
-		sorted, err = json.MarshalIndent(unsorted, "", "\t")
-	} else {
-		sorted, err = json.Marshal(unsorted)
-	}
-	if err != nil {
-		return
+		indentString = JSON_INDENT
 	}
 
-	// replace the name placeholders
-	replaced = ReplaceNamePlaceholders(sorted)
+	if replaced, err = NewJsonCanonicalizer(KeyOrder, indentString).CanonicalizeBytes(data, metadataType); err != nil {
+		logging.Get().Tracef("Error canonicalizing json: %v", string(data))
+	}
 
 	return
 }
@@ -0,0 +1,20 @@
+package util
+
+import (
+	"io"
+	"os"
+)
+
+// IsTerminal returns true if the reader is a terminal that someone can answer
+// prompts from
+func IsTerminal(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,21 @@
+package util_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestIsTerminal(t *testing.T) {
+	assert.False(t, util.IsTerminal(strings.NewReader("yes")))
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "answers"))
+	assert.NoError(t, err)
+	defer file.Close()
+	assert.False(t, util.IsTerminal(file))
+}
@@ -0,0 +1,124 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+var (
+	// placeholderExpression matches ${SKUID_ENV:NAME}
+	placeholderExpression = regexp.MustCompile(`\$\{SKUID_ENV:([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// Variables are the values substituted for ${SKUID_ENV:NAME} placeholders in
+// deployed metadata
+type Variables map[string]string
+
+// ReadVariablesFile reads variables from an env file
+func ReadVariablesFile(path string) (variables Variables, err error) {
+	var values map[string]string
+	if values, err = godotenv.Read(path); err != nil {
+		err = fmt.Errorf("unable to read variables from %v: %w", path, err)
+		return
+	}
+	return Variables(values), nil
+}
+
+// Merge returns the variables with the other variables' values taking
+// precedence. Names are overridden regardless of case, since names read
+// from the config file are lower cased.
+func (variables Variables) Merge(other Variables) (merged Variables) {
+	merged = make(Variables, len(variables)+len(other))
+	for name, value := range variables {
+		merged[name] = value
+	}
+	for name, value := range other {
+		for existing := range merged {
+			if strings.EqualFold(existing, name) {
+				delete(merged, existing)
+			}
+		}
+		merged[name] = value
+	}
+	return
+}
+
+// lookup finds a variable by name, falling back to a case insensitive match
+// for names read from the config file
+func (variables Variables) lookup(name string) (value string, ok bool) {
+	if value, ok = variables[name]; ok {
+		return
+	}
+	for candidate, candidateValue := range variables {
+		if strings.EqualFold(candidate, name) {
+			return candidateValue, true
+		}
+	}
+	return
+}
+
+// UnresolvedPlaceholdersError lists the placeholders in a file that have no value
+type UnresolvedPlaceholdersError struct {
+	Path  string
+	Names []string
+}
+
+func (e UnresolvedPlaceholdersError) Error() string {
+	return fmt.Sprintf("%v has unresolved placeholders: %v", e.Path, strings.Join(e.Names, ", "))
+}
+
+// escapeVariable escapes a value so it can be placed anywhere a placeholder
+// can be in the file: inside a json string, or in xml text or attributes
+func escapeVariable(path, value string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var encoded bytes.Buffer
+		encoder := json.NewEncoder(&encoded)
+		encoder.SetEscapeHTML(false)
+		_ = encoder.Encode(value)
+		return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(encoded.String()), `"`), `"`)
+	case ".xml":
+		var escaped bytes.Buffer
+		_ = xml.EscapeText(&escaped, []byte(value))
+		return escaped.String()
+	default:
+		return value
+	}
+}
+
+// Substitute replaces the placeholders in a file's data. The path (relative
+// or not) decides how values are escaped. Any placeholder without a value is
+// an UnresolvedPlaceholdersError. Data without placeholders is returned as is.
+func (variables Variables) Substitute(path string, data []byte) (result []byte, err error) {
+	if !bytes.Contains(data, []byte("${SKUID_ENV:")) {
+		return data, nil
+	}
+
+	var unresolved []string
+	result = placeholderExpression.ReplaceAllFunc(data, func(placeholder []byte) []byte {
+		name := string(placeholderExpression.FindSubmatch(placeholder)[1])
+		value, ok := variables.lookup(name)
+		if !ok {
+			if !StringSliceContainsKey(unresolved, name) {
+				unresolved = append(unresolved, name)
+			}
+			return placeholder
+		}
+		return []byte(escapeVariable(path, value))
+	})
+
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		err = UnresolvedPlaceholdersError{Path: filepath.ToSlash(path), Names: unresolved}
+		result = nil
+	}
+	return
+}
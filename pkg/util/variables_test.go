@@ -0,0 +1,100 @@
+package util_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestVariablesSubstitute(t *testing.T) {
+	variables := util.Variables{
+		"DATASOURCE_URL": "https://api.example.com",
+		"QUOTED":         `say "hi" & <bye>`,
+		"client_id":      "abc",
+	}
+
+	for _, tc := range []struct {
+		description string
+		path        string
+		given       string
+		expected    string
+		unresolved  []string
+	}{
+		{
+			description: "json",
+			path:        "datasources/Orders.json",
+			given:       `{"url":"${SKUID_ENV:DATASOURCE_URL}/v1","note":"${SKUID_ENV:QUOTED}"}`,
+			expected:    `{"url":"https://api.example.com/v1","note":"say \"hi\" & <bye>"}`,
+		},
+		{
+			description: "xml",
+			path:        "pages/Home.xml",
+			given:       `<model url="${SKUID_ENV:QUOTED}"/>`,
+			expected:    `<model url="say &#34;hi&#34; &amp; &lt;bye&gt;"/>`,
+		},
+		{
+			description: "other files are unescaped",
+			path:        "files/config.txt",
+			given:       `${SKUID_ENV:QUOTED}`,
+			expected:    `say "hi" & <bye>`,
+		},
+		{
+			description: "names from the config file are case insensitive",
+			path:        "authproviders/Okta.json",
+			given:       `{"clientId":"${SKUID_ENV:CLIENT_ID}"}`,
+			expected:    `{"clientId":"abc"}`,
+		},
+		{
+			description: "no placeholders",
+			path:        "pages/Home.json",
+			given:       `{"name":"Home","price":"${5}"}`,
+			expected:    `{"name":"Home","price":"${5}"}`,
+		},
+		{
+			description: "unresolved",
+			path:        "datasources/Orders.json",
+			given:       `{"a":"${SKUID_ENV:MISSING}","b":"${SKUID_ENV:ALSO_MISSING}","c":"${SKUID_ENV:MISSING}"}`,
+			unresolved:  []string{"ALSO_MISSING", "MISSING"},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			actual, err := variables.Substitute(tc.path, []byte(tc.given))
+			if tc.unresolved != nil {
+				var unresolvedErr util.UnresolvedPlaceholdersError
+				if assert.True(t, errors.As(err, &unresolvedErr)) {
+					assert.Equal(t, tc.unresolved, unresolvedErr.Names)
+					assert.Equal(t, tc.path, unresolvedErr.Path)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+
+	// without any variables, placeholders are still unresolved
+	var none util.Variables
+	_, err := none.Substitute("pages/Home.json", []byte(`"${SKUID_ENV:A}"`))
+	assert.Error(t, err)
+}
+
+func TestReadVariablesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prod.env")
+	assert.NoError(t, os.WriteFile(path, []byte("# prod\nDATASOURCE_URL=https://prod\nexport CLIENT_ID=\"abc\"\n"), 0644))
+
+	variables, err := util.ReadVariablesFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, util.Variables{"DATASOURCE_URL": "https://prod", "CLIENT_ID": "abc"}, variables)
+
+	merged := variables.Merge(util.Variables{"CLIENT_ID": "override"})
+	assert.Equal(t, "override", merged["CLIENT_ID"])
+	assert.Equal(t, "abc", variables["CLIENT_ID"])
+
+	_, err = util.ReadVariablesFile(filepath.Join(t.TempDir(), "missing.env"))
+	assert.Error(t, err)
+}
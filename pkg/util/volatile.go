@@ -0,0 +1,64 @@
+package util
+
+import (
+	"strings"
+)
+
+var (
+	// DefaultVolatileFields are json paths, by metadata type, for values
+	// that change on every retrieve without anything meaningful changing.
+	// None of them are required to deploy.
+	DefaultVolatileFields = map[string][]string{
+		"apps": {
+			"created_at",
+			"updated_at",
+			"created_by_id",
+			"updated_by_id",
+		},
+		"datasources": {
+			"created_at",
+			"updated_at",
+			"created_by_id",
+			"updated_by_id",
+		},
+		"site": {
+			"created_at",
+			"updated_at",
+			"created_by_id",
+			"updated_by_id",
+			"last_published_at",
+		},
+	}
+
+	// VolatileFields are the volatile fields removed from retrieved metadata
+	VolatileFields = CopyVolatileFields(DefaultVolatileFields)
+)
+
+// CopyVolatileFields returns a copy of the volatile fields that is safe to modify
+func CopyVolatileFields(from map[string][]string) (to map[string][]string) {
+	to = make(map[string][]string, len(from))
+	for metadataType, paths := range from {
+		to[metadataType] = append([]string{}, paths...)
+	}
+	return
+}
+
+// SetVolatileFields replaces the volatile fields for each metadata type in
+// the overrides. Types that aren't overridden keep their current fields, and
+// an empty list turns stripping off for a type.
+func SetVolatileFields(overrides map[string][]string) {
+	for metadataType, paths := range overrides {
+		cleaned := make([]string, 0, len(paths))
+		for _, path := range paths {
+			if path = strings.TrimSpace(path); path != "" {
+				cleaned = append(cleaned, path)
+			}
+		}
+		VolatileFields[strings.ToLower(metadataType)] = cleaned
+	}
+}
+
+// ClearVolatileFields keeps every field on retrieve
+func ClearVolatileFields() {
+	VolatileFields = make(map[string][]string)
+}
@@ -0,0 +1,57 @@
+package util_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestJsonCanonicalizerVolatileFields(t *testing.T) {
+	canonicalizer := util.NewJsonCanonicalizer(util.DefaultKeyOrderPolicy, "")
+	canonicalizer.VolatileFields = map[string][]string{
+		"datasources": {"updated_at", "config.lastSync", "fields.id", "models.*.meta.etag"},
+	}
+
+	given := `{"name":"ds","updated_at":"2023-01-01","config":{"lastSync":1,"url":"x"},"fields":[{"id":123,"name":"a"},{"name":"b"}],"models":{"m1":{"meta":{"etag":"1","k":2}},"m2":{"meta":{"etag":"2"}}}}`
+
+	actual, err := canonicalizer.CanonicalizeBytes([]byte(given), "datasources")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"ds","config":{"url":"x"},"fields":[{"name":"a"},{"name":"b"}],"models":{"m1":{"meta":{"k":2}},"m2":{"meta":{}}}}`, string(actual))
+
+	// other types keep everything
+	actual, err = canonicalizer.CanonicalizeBytes([]byte(`{"updated_at":"2023-01-01","name":"p"}`), "pages")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"p","updated_at":"2023-01-01"}`, string(actual))
+}
+
+func TestSanitizeZipForType(t *testing.T) {
+	defer func() { util.VolatileFields = util.CopyVolatileFields(util.DefaultVolatileFields) }()
+
+	given := `{"updated_at":"2023-01-01T00:00:00Z","name":"ds","created_by_id":"abc","url":"https://example.com"}`
+	sanitize := func(metadataType string) string {
+		reader, err := util.SanitizeZipForType(io.NopCloser(bytes.NewBufferString(given)), metadataType)
+		assert.NoError(t, err)
+		b, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, "{\n\t\"name\": \"ds\",\n\t\"url\": \"https://example.com\"\n}", sanitize("datasources"))
+	assert.Contains(t, sanitize("pages"), "updated_at")
+
+	util.SetVolatileFields(map[string][]string{
+		"Pages":       {" url ", ""},
+		"datasources": {},
+	})
+	assert.NotContains(t, sanitize("pages"), "url")
+	assert.Contains(t, sanitize("pages"), "updated_at")
+	assert.Contains(t, sanitize("datasources"), "updated_at")
+	assert.NotContains(t, sanitize("site"), "updated_at")
+
+	util.ClearVolatileFields()
+	assert.Contains(t, sanitize("site"), "updated_at")
+}
@@ -0,0 +1,452 @@
+package util
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/skuid/skuid-cli/pkg/logging"
+)
+
+const (
+	XML_INDENT = "\t"
+)
+
+type xmlNodeKind int
+
+const (
+	xmlElement xmlNodeKind = iota
+	xmlText
+	xmlCData
+	xmlComment
+	xmlProcInst
+	xmlDirective
+)
+
+type xmlAttr struct {
+	name  string
+	value string // raw value, entities are left as-is
+	quote byte
+}
+
+// xmlNode is a piece of an xml document. Everything other than elements
+// keeps its raw source so that it can be written back exactly.
+type xmlNode struct {
+	kind     xmlNodeKind
+	name     string
+	attrs    []xmlAttr
+	children []*xmlNode
+	raw      []byte
+	// inner is the raw source between the start and end tags of an element
+	inner []byte
+}
+
+// structural returns true if the element only contains other elements,
+// comments and whitespace, which means we're free to re-indent it. Anything
+// with text or CDATA (script content, inline html) is written as we got it.
+func (node *xmlNode) structural() bool {
+	for _, child := range node.children {
+		switch child.kind {
+		case xmlCData:
+			return false
+		case xmlText:
+			if len(bytes.TrimSpace(child.raw)) > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (node *xmlNode) hasChildElements() bool {
+	for _, child := range node.children {
+		if child.kind != xmlText {
+			return true
+		}
+	}
+	return false
+}
+
+type xmlScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *xmlScanner) errorf(msg string, args ...interface{}) error {
+	line := bytes.Count(s.data[:s.pos], []byte("\n")) + 1
+	return fmt.Errorf("xml syntax error on line %v: %v", line, fmt.Sprintf(msg, args...))
+}
+
+// until advances past the terminator and returns everything up to and including it
+func (s *xmlScanner) until(terminator string, what string) (raw []byte, err error) {
+	index := bytes.Index(s.data[s.pos:], []byte(terminator))
+	if index < 0 {
+		return nil, s.errorf("unterminated %v", what)
+	}
+	end := s.pos + index + len(terminator)
+	raw = s.data[s.pos:end]
+	s.pos = end
+	return
+}
+
+func isXmlSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+func (s *xmlScanner) skipSpace() {
+	for s.pos < len(s.data) && isXmlSpace(s.data[s.pos]) {
+		s.pos++
+	}
+}
+
+func (s *xmlScanner) name() string {
+	start := s.pos
+	for s.pos < len(s.data) {
+		b := s.data[s.pos]
+		if isXmlSpace(b) || b == '/' || b == '>' || b == '=' {
+			break
+		}
+		s.pos++
+	}
+	return string(s.data[start:s.pos])
+}
+
+// parseNodes reads nodes until it finds the end tag for the parent
+func (s *xmlScanner) parseNodes(parent string) (nodes []*xmlNode, innerEnd int, err error) {
+	for s.pos < len(s.data) {
+		start := s.pos
+		switch {
+		case s.data[s.pos] != '<':
+			index := bytes.IndexByte(s.data[s.pos:], '<')
+			if index < 0 {
+				index = len(s.data) - s.pos
+			}
+			s.pos += index
+			nodes = append(nodes, &xmlNode{kind: xmlText, raw: s.data[start:s.pos]})
+		case bytes.HasPrefix(s.data[s.pos:], []byte("<?")):
+			var raw []byte
+			if raw, err = s.until("?>", "processing instruction"); err != nil {
+				return
+			}
+			nodes = append(nodes, &xmlNode{kind: xmlProcInst, raw: raw})
+		case bytes.HasPrefix(s.data[s.pos:], []byte("<!--")):
+			var raw []byte
+			if raw, err = s.until("-->", "comment"); err != nil {
+				return
+			}
+			nodes = append(nodes, &xmlNode{kind: xmlComment, raw: raw})
+		case bytes.HasPrefix(s.data[s.pos:], []byte("<![CDATA[")):
+			var raw []byte
+			if raw, err = s.until("]]>", "CDATA section"); err != nil {
+				return
+			}
+			nodes = append(nodes, &xmlNode{kind: xmlCData, raw: raw})
+		case bytes.HasPrefix(s.data[s.pos:], []byte("<!")):
+			// doctype and friends, which may have an internal subset in brackets
+			depth := 0
+			for s.pos < len(s.data) {
+				b := s.data[s.pos]
+				s.pos++
+				if b == '[' {
+					depth++
+				} else if b == ']' {
+					depth--
+				} else if b == '>' && depth <= 0 {
+					break
+				}
+			}
+			nodes = append(nodes, &xmlNode{kind: xmlDirective, raw: s.data[start:s.pos]})
+		case bytes.HasPrefix(s.data[s.pos:], []byte("</")):
+			s.pos += 2
+			name := s.name()
+			s.skipSpace()
+			if s.pos >= len(s.data) || s.data[s.pos] != '>' {
+				err = s.errorf("malformed end tag </%v>", name)
+				return
+			}
+			s.pos++
+			if name != parent {
+				err = s.errorf("element <%v> closed by </%v>", parent, name)
+				return
+			}
+			innerEnd = start
+			return
+		default:
+			var node *xmlNode
+			if node, err = s.parseElement(); err != nil {
+				return
+			}
+			nodes = append(nodes, node)
+		}
+	}
+
+	if parent != "" {
+		err = s.errorf("element <%v> is never closed", parent)
+	}
+	innerEnd = s.pos
+	return
+}
+
+func (s *xmlScanner) parseElement() (node *xmlNode, err error) {
+	s.pos++ // <
+	node = &xmlNode{kind: xmlElement, name: s.name()}
+	if node.name == "" {
+		return nil, s.errorf("missing element name")
+	}
+
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.data) {
+			return nil, s.errorf("unterminated start tag <%v>", node.name)
+		}
+		switch s.data[s.pos] {
+		case '/':
+			if s.pos+1 >= len(s.data) || s.data[s.pos+1] != '>' {
+				return nil, s.errorf("malformed start tag <%v>", node.name)
+			}
+			s.pos += 2
+			return
+		case '>':
+			s.pos++
+			innerStart := s.pos
+			var innerEnd int
+			if node.children, innerEnd, err = s.parseNodes(node.name); err != nil {
+				return
+			}
+			node.inner = s.data[innerStart:innerEnd]
+			return
+		}
+
+		attr := xmlAttr{name: s.name()}
+		s.skipSpace()
+		if attr.name == "" || s.pos >= len(s.data) || s.data[s.pos] != '=' {
+			return nil, s.errorf("malformed attribute in <%v>", node.name)
+		}
+		s.pos++
+		s.skipSpace()
+		if s.pos >= len(s.data) || (s.data[s.pos] != '"' && s.data[s.pos] != '\'') {
+			return nil, s.errorf("unquoted attribute %v in <%v>", attr.name, node.name)
+		}
+		attr.quote = s.data[s.pos]
+		s.pos++
+		index := bytes.IndexByte(s.data[s.pos:], attr.quote)
+		if index < 0 {
+			return nil, s.errorf("unterminated attribute %v in <%v>", attr.name, node.name)
+		}
+		attr.value = string(s.data[s.pos : s.pos+index])
+		s.pos += index + 1
+		node.attrs = append(node.attrs, attr)
+	}
+}
+
+// attrLess puts namespace declarations first, then everything alphabetically
+func attrLess(a, b xmlAttr) bool {
+	aNs := a.name == "xmlns" || strings.HasPrefix(a.name, "xmlns:")
+	bNs := b.name == "xmlns" || strings.HasPrefix(b.name, "xmlns:")
+	if aNs != bNs {
+		return aNs
+	}
+	return a.name < b.name
+}
+
+func writeXmlNode(w *bytes.Buffer, node *xmlNode, depth int) {
+	w.WriteString(strings.Repeat(XML_INDENT, depth))
+	if node.kind != xmlElement {
+		w.Write(node.raw)
+		return
+	}
+
+	w.WriteByte('<')
+	w.WriteString(node.name)
+	attrs := append([]xmlAttr{}, node.attrs...)
+	sort.SliceStable(attrs, func(i, j int) bool { return attrLess(attrs[i], attrs[j]) })
+	for _, attr := range attrs {
+		value := attr.value
+		if attr.quote == '\'' {
+			value = strings.ReplaceAll(value, `"`, "&quot;")
+		}
+		fmt.Fprintf(w, ` %v="%v"`, attr.name, value)
+	}
+
+	switch {
+	case len(node.inner) == 0:
+		w.WriteString("/>")
+	case !node.structural() || !node.hasChildElements():
+		// text, even if it's only whitespace, is content (e.g. a label of
+		// a single space), so only truly empty elements are self-closed
+		w.WriteByte('>')
+		w.Write(node.inner)
+		fmt.Fprintf(w, "</%v>", node.name)
+	default:
+		w.WriteByte('>')
+		for _, child := range node.children {
+			if child.kind == xmlText {
+				continue
+			}
+			w.WriteByte('\n')
+			writeXmlNode(w, child, depth+1)
+		}
+		w.WriteByte('\n')
+		w.WriteString(strings.Repeat(XML_INDENT, depth))
+		fmt.Fprintf(w, "</%v>", node.name)
+	}
+}
+
+// NormalizeXml rewrites an xml document with stable indentation, sorted
+// attributes and self-closing empty elements. Elements containing text or
+// CDATA (e.g. javascript and css resources), or only whitespace, are written
+// exactly as received.
+func NormalizeXml(data []byte) (normalized []byte, err error) {
+	scanner := &xmlScanner{data: data}
+	var nodes []*xmlNode
+	if nodes, _, err = scanner.parseNodes(""); err != nil {
+		return
+	}
+
+	var buffer bytes.Buffer
+	first := true
+	for _, node := range nodes {
+		if node.kind == xmlText {
+			if len(bytes.TrimSpace(node.raw)) > 0 {
+				err = fmt.Errorf("xml syntax error: text outside of the root element")
+				return
+			}
+			continue
+		}
+		if node.kind == xmlCData {
+			err = fmt.Errorf("xml syntax error: CDATA outside of the root element")
+			return
+		}
+		if !first {
+			buffer.WriteByte('\n')
+		}
+		first = false
+		writeXmlNode(&buffer, node, 0)
+	}
+
+	normalized = buffer.Bytes()
+	return
+}
+
+// xmlEvents reduces a document to the things that matter to an xml parser:
+// element names, attributes regardless of order, text (with CDATA treated as
+// text), comments and processing instructions. Whitespace between elements
+// doesn't matter, but the whitespace of an element with only text does.
+func xmlEvents(data []byte) (events []string, err error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	// whitespace is only kept if it turns out to be text, rather than
+	// indentation between elements
+	var space string
+	for {
+		var token xml.Token
+		if token, err = decoder.RawToken(); err == io.EOF {
+			err = nil
+			return
+		} else if err != nil {
+			return
+		}
+
+		if _, isText := token.(xml.CharData); !isText && space != "" && len(events) > 0 {
+			// whitespace ends a run of text, and an element with only
+			// whitespace in it has that as its text
+			last := len(events) - 1
+			_, isEnd := token.(xml.EndElement)
+			if strings.HasPrefix(events[last], "text:") {
+				events[last] += space
+			} else if isEnd && strings.HasPrefix(events[last], "<") && !strings.HasPrefix(events[last], "</") {
+				events = append(events, "text:"+space)
+			}
+			space = ""
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			attrs := make([]string, 0, len(t.Attr))
+			for _, attr := range t.Attr {
+				attrs = append(attrs, fmt.Sprintf("%v:%v=%q", attr.Name.Space, attr.Name.Local, attr.Value))
+			}
+			sort.Strings(attrs)
+			events = append(events, fmt.Sprintf("<%v:%v %v>", t.Name.Space, t.Name.Local, strings.Join(attrs, " ")))
+		case xml.EndElement:
+			events = append(events, fmt.Sprintf("</%v:%v>", t.Name.Space, t.Name.Local))
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				space += string(t)
+				continue
+			}
+			// adjacent text and CDATA are one run of text to a parser
+			if last := len(events) - 1; last >= 0 && strings.HasPrefix(events[last], "text:") {
+				events[last] += space + string(t)
+			} else {
+				events = append(events, "text:"+space+string(t))
+			}
+			space = ""
+		case xml.Comment:
+			events = append(events, "comment:"+string(t))
+		case xml.ProcInst:
+			events = append(events, fmt.Sprintf("pi:%v %v", t.Target, string(t.Inst)))
+		case xml.Directive:
+			events = append(events, "directive:"+string(t))
+		}
+	}
+}
+
+// XmlEquivalent returns true if both documents are the same to an xml parser,
+// ignoring attribute order and whitespace between elements, but not the
+// whitespace of elements with only text
+func XmlEquivalent(a, b []byte) (equivalent bool, err error) {
+	var aEvents, bEvents []string
+	if aEvents, err = xmlEvents(a); err != nil {
+		return
+	}
+	if bEvents, err = xmlEvents(b); err != nil {
+		return
+	}
+	if len(aEvents) != len(bEvents) {
+		return
+	}
+	for i := range aEvents {
+		if aEvents[i] != bEvents[i] {
+			return
+		}
+	}
+	equivalent = true
+	return
+}
+
+// SanitizeXml normalizes retrieved xml. If the xml can't be normalized, or the
+// normalized xml wouldn't be read the same way, we keep what we received.
+func SanitizeXml(reader io.ReadCloser) (newReader io.ReadCloser, err error) {
+	var b []byte
+	if b, err = io.ReadAll(reader); err != nil {
+		logging.Get().Warnf("unable to read all: %v", err)
+		return
+	}
+	defer reader.Close()
+
+	newReader = io.NopCloser(bytes.NewBuffer(b))
+
+	normalized, e := NormalizeXml(b)
+	if e != nil {
+		logging.Get().Warnf("unable to normalize xml, keeping original: %v", e)
+		return
+	}
+
+	if equivalent, e := XmlEquivalent(b, normalized); e != nil {
+		logging.Get().Warnf("unable to compare normalized xml, keeping original: %v", e)
+		return
+	} else if !equivalent {
+		logging.Get().Warn("normalized xml does not match original, keeping original")
+		return
+	}
+
+	newReader = io.NopCloser(bytes.NewBuffer(normalized))
+
+	return
+}
@@ -0,0 +1,162 @@
+package util_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+const messyPageXml = `<?xml version="1.0" encoding="UTF-8"?>
+<skuid__page unsavedchangeswarning="yes" personalizationmode="server" showsidebar="true">
+  <models>
+        <model id="Orders" limit="20" datasource="Orders"   query="true">
+      <fields><field id="Name"></field><field id='Total' label='The "total"'/></fields>
+    </model>
+  </models>
+  <components/>
+  <resources>
+    <labels></labels>
+    <label> </label>
+    <javascript>
+      <jsitem location="inline" name="init" cachelocation="false"><![CDATA[if (a < b && c) {
+    console.log("<b>  spaced  </b>");
+}]]></jsitem>
+    </javascript>
+    <styles><styleitem location="inline" name="s">  .a > .b { color: red; }  </styleitem></styles>
+  </resources>
+  <!-- keep me -->
+  <template>Hello &amp; <b>welcome</b>   back</template>
+</skuid__page>`
+
+const normalizedPageXml = `<?xml version="1.0" encoding="UTF-8"?>
+<skuid__page personalizationmode="server" showsidebar="true" unsavedchangeswarning="yes">
+	<models>
+		<model datasource="Orders" id="Orders" limit="20" query="true">
+			<fields>
+				<field id="Name"/>
+				<field id="Total" label="The &quot;total&quot;"/>
+			</fields>
+		</model>
+	</models>
+	<components/>
+	<resources>
+		<labels/>
+		<label> </label>
+		<javascript>
+			<jsitem cachelocation="false" location="inline" name="init"><![CDATA[if (a < b && c) {
+    console.log("<b>  spaced  </b>");
+}]]></jsitem>
+		</javascript>
+		<styles>
+			<styleitem location="inline" name="s">  .a > .b { color: red; }  </styleitem>
+		</styles>
+	</resources>
+	<!-- keep me -->
+	<template>Hello &amp; <b>welcome</b>   back</template>
+</skuid__page>`
+
+func TestNormalizeXml(t *testing.T) {
+	actual, err := util.NormalizeXml([]byte(messyPageXml))
+	assert.NoError(t, err)
+	assert.Equal(t, normalizedPageXml, string(actual))
+
+	// normalizing is idempotent
+	again, err := util.NormalizeXml(actual)
+	assert.NoError(t, err)
+	assert.Equal(t, string(actual), string(again))
+
+	// and round trips to the same document
+	equivalent, err := util.XmlEquivalent([]byte(messyPageXml), actual)
+	assert.NoError(t, err)
+	assert.True(t, equivalent)
+}
+
+func TestNormalizeXmlErrors(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		given       string
+	}{
+		{description: "mismatched tags", given: `<a><b></a></b>`},
+		{description: "never closed", given: `<a><b/>`},
+		{description: "unterminated cdata", given: `<a><![CDATA[ x </a>`},
+		{description: "unquoted attribute", given: `<a b=c/>`},
+		{description: "text outside root", given: `<a/>text`},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := util.NormalizeXml([]byte(tc.given))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestXmlEquivalent(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		a           string
+		b           string
+		expected    bool
+	}{
+		{description: "attribute order", a: `<a x="1" y="2"/>`, b: `<a y="2" x="1"></a>`, expected: true},
+		{description: "whitespace between elements", a: "<a>\n  <b/>\n</a>", b: `<a><b/></a>`, expected: true},
+		{description: "cdata and text", a: `<a><![CDATA[x < y]]></a>`, b: `<a>x &lt; y</a>`, expected: true},
+		{description: "different attribute", a: `<a x="1"/>`, b: `<a x="2"/>`, expected: false},
+		{description: "different text", a: `<a>one</a>`, b: `<a>two</a>`, expected: false},
+		{description: "different nesting", a: `<a><b/><c/></a>`, b: `<a><b><c/></b></a>`, expected: false},
+		{description: "whitespace text", a: `<a><label> </label></a>`, b: `<a><label/></a>`, expected: false},
+		{description: "different whitespace text", a: `<a><label> </label></a>`, b: `<a><label>  </label></a>`, expected: false},
+		{description: "whitespace after cdata", a: `<a><![CDATA[x]]> </a>`, b: `<a><![CDATA[x]]></a>`, expected: false},
+		{description: "whitespace after an element", a: "<a><b/>\n</a>", b: `<a><b/></a>`, expected: true},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			actual, err := util.XmlEquivalent([]byte(tc.a), []byte(tc.b))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestUnzipArchiveNormalizesPageXml(t *testing.T) {
+	util.ResetPathMap()
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, body := range map[string]string{
+		"pages/MyPage.xml":   messyPageXml,
+		"files/MyFile.xml":   messyPageXml,
+		"pages/Broken.xml":   `<a><b></a>`,
+		"themes/MyTheme.xml": messyPageXml,
+	} {
+		f, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = f.Write([]byte(body))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	source := filepath.Join(t.TempDir(), "retrieve.zip")
+	assert.NoError(t, os.WriteFile(source, buf.Bytes(), 0644))
+
+	written := map[string]string{}
+	err := util.UnzipArchive(source, "",
+		func(reader io.ReadCloser, path string) error {
+			body, err := io.ReadAll(reader)
+			written[filepath.ToSlash(path)] = string(body)
+			return err
+		},
+		func(path string, fileMode os.FileMode) error { return nil },
+		os.ReadFile,
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, normalizedPageXml, written["pages/MyPage.xml"])
+	// only page xml is normalized, and broken xml is kept as we got it
+	assert.Equal(t, messyPageXml, written["files/MyFile.xml"])
+	assert.Equal(t, messyPageXml, written["themes/MyTheme.xml"])
+	assert.Equal(t, `<a><b></a>`, written["pages/Broken.xml"])
+}
@@ -0,0 +1,282 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+// ValidationProblem is something in a file that would fail a deploy
+type ValidationProblem struct {
+	// Path is relative to the deployed directory, with forward slashes
+	Path string `json:"path"`
+	// Line is 1-based, or 0 when the problem isn't on a line
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (problem ValidationProblem) String() string {
+	if problem.Line > 0 {
+		return fmt.Sprintf("%v:%v: %v", problem.Path, problem.Line, problem.Message)
+	}
+	return fmt.Sprintf("%v: %v", problem.Path, problem.Message)
+}
+
+// pageReference is an entity that a page or app refers to, and where
+type pageReference struct {
+	path         string
+	line         int
+	metadataType string
+	name         string
+	referrer     string
+}
+
+// appRoutes is the portion of an app's metadata that refers to its pages
+type appRoutes struct {
+	Routes []struct {
+		Page string `json:"page"`
+	} `json:"routes"`
+}
+
+// ValidateDirectory checks the files that a deploy of the directory would
+// archive, limited to the filter's entities if there is one: that every .json
+// file parses, that every page's .xml is well formed, that pages and themes
+// have their companion files, and that the themes and data sources pages use
+// and the pages apps route to exist locally or on the site. The site's
+// entities are only asked for when something isn't found locally.
+func ValidateDirectory(targetDir string, filter *NlxMetadata, variables util.Variables, remote func() (NlxMetadata, error)) (problems []ValidationProblem, err error) {
+	var keep func(string) bool
+	if filter != nil {
+		keep = filter.FilterItem
+	}
+
+	var files map[string]string
+	if files, err = metadataFiles(targetDir, keep); err != nil {
+		return
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var references []pageReference
+	for _, path := range paths {
+		var data []byte
+		if data, err = os.ReadFile(files[path]); err != nil {
+			return
+		}
+		// check what would be deployed; unresolved placeholders fail the
+		// archive on their own
+		if substituted, substituteErr := variables.Substitute(path, data); substituteErr == nil {
+			data = substituted
+		}
+
+		metadataType, name, _ := EntityFromPath(path)
+		switch {
+		case strings.HasSuffix(path, ".json"):
+			if problem, ok := validateJson(path, data); !ok {
+				problems = append(problems, problem)
+				continue
+			}
+			if metadataType == "apps" && !strings.HasSuffix(path, ".skuid.json") {
+				references = append(references, appReferences(path, name, data)...)
+			}
+		case metadataType == "pages" && strings.HasSuffix(path, ".xml"):
+			var pageReferences []pageReference
+			var problem *ValidationProblem
+			if pageReferences, problem = pageXmlReferences(path, name, data); problem != nil {
+				problems = append(problems, *problem)
+				continue
+			}
+			references = append(references, pageReferences...)
+		}
+	}
+
+	problems = append(problems, missingCompanions(paths)...)
+
+	var referenceProblems []ValidationProblem
+	if referenceProblems, err = unresolvedReferences(targetDir, references, remote); err != nil {
+		return
+	}
+	problems = append(problems, referenceProblems...)
+	return
+}
+
+// validateJson returns where the data stops being valid json
+func validateJson(path string, data []byte) (problem ValidationProblem, ok bool) {
+	var value any
+	err := json.Unmarshal(data, &value)
+	if err == nil {
+		return problem, true
+	}
+
+	problem = ValidationProblem{Path: path, Message: fmt.Sprintf("invalid json: %v", err)}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		problem.Line = lineAt(data, syntaxErr.Offset)
+	}
+	return
+}
+
+// builtInDataSources are the data sources that every site has without them
+// being metadata, such as the one for models that only exist in the page
+var builtInDataSources = []string{"Ui-Only"}
+
+func builtInDataSource(name string) bool {
+	for _, builtIn := range builtInDataSources {
+		if strings.EqualFold(name, builtIn) {
+			return true
+		}
+	}
+	return false
+}
+
+// pageXmlReferences checks that a page's xml is well formed, and returns the
+// theme and data sources it uses
+func pageXmlReferences(path, page string, data []byte) (references []pageReference, problem *ValidationProblem) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	root := true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			line, _ := decoder.InputPos()
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line = syntaxErr.Line
+			}
+			problem = &ValidationProblem{Path: path, Line: line, Message: fmt.Sprintf("invalid xml: %v", err)}
+			return
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		line, _ := decoder.InputPos()
+		for _, attr := range element.Attr {
+			switch {
+			case root && attr.Name.Local == "theme" && attr.Value != "":
+				references = append(references, pageReference{path, line, "themes", attr.Value, "page " + page})
+			case element.Name.Local == "model" && attr.Name.Local == "datasource" && attr.Value != "" && !builtInDataSource(attr.Value):
+				references = append(references, pageReference{path, line, "datasources", attr.Value, "page " + page})
+			}
+		}
+		root = false
+	}
+}
+
+// appReferences returns the pages that an app's routes go to
+func appReferences(path, app string, data []byte) (references []pageReference) {
+	var routes appRoutes
+	if json.Unmarshal(data, &routes) != nil {
+		return
+	}
+	for _, route := range routes.Routes {
+		if route.Page == "" {
+			continue
+		}
+		quoted, _ := json.Marshal(route.Page)
+		references = append(references, pageReference{path, lineAt(data, int64(bytes.Index(data, quoted))), "pages", route.Page, "app " + app})
+	}
+	return
+}
+
+// missingCompanions returns the pages without both their .xml and their
+// .json (or .skuid.json), and the themes without their .inline.css
+func missingCompanions(paths []string) (problems []ValidationProblem) {
+	has := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		has[path] = true
+	}
+
+	for _, path := range paths {
+		metadataType, name, _ := EntityFromPath(path)
+		switch {
+		case metadataType == "pages" && strings.HasSuffix(path, ".xml"):
+			base := strings.TrimSuffix(path, ".xml")
+			if !has[base+".json"] && !has[base+".skuid.json"] {
+				problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf("page %v has no %v.json or %v.skuid.json", name, name, name)})
+			}
+		case metadataType == "pages" && strings.HasSuffix(path, ".json"):
+			base := strings.TrimSuffix(strings.TrimSuffix(path, ".json"), ".skuid")
+			if !has[base+".xml"] {
+				problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf("page %v has no %v.xml", name, name)})
+			}
+		case metadataType == "themes" && strings.HasSuffix(path, ".json"):
+			if base := strings.TrimSuffix(path, ".json"); !has[base+".inline.css"] {
+				problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf("theme %v has no %v.inline.css", name, name)})
+			}
+		}
+	}
+	return
+}
+
+// unresolvedReferences returns the references to entities that are neither
+// in the directory nor on the site
+func unresolvedReferences(targetDir string, references []pageReference, remote func() (NlxMetadata, error)) (problems []ValidationProblem, err error) {
+	if len(references) == 0 {
+		return
+	}
+
+	var local NlxMetadata
+	if local, err = metadataInDirectory(targetDir); err != nil {
+		return
+	}
+	exists := func(metadata NlxMetadata, reference pageReference) bool {
+		names, _ := metadata.GetFieldValueByName(reference.metadataType)
+		return util.StringSliceContainsKey(names, reference.name)
+	}
+
+	var missing []pageReference
+	for _, reference := range references {
+		if !exists(local, reference) {
+			missing = append(missing, reference)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	var site NlxMetadata
+	if remote != nil {
+		if site, err = remote(); err != nil {
+			return
+		}
+	}
+	for _, reference := range missing {
+		if exists(site, reference) {
+			continue
+		}
+		problems = append(problems, ValidationProblem{
+			Path:    reference.path,
+			Line:    reference.line,
+			Message: fmt.Sprintf("%v refers to %v %v, which isn't in the directory or on the site", reference.referrer, strings.TrimSuffix(reference.metadataType, "s"), reference.name),
+		})
+	}
+	return
+}
+
+// lineAt returns the 1-based line of the byte offset in the data
+func lineAt(data []byte, offset int64) int {
+	if offset < 0 {
+		return 0
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
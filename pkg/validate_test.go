@@ -0,0 +1,142 @@
+package pkg_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+	"github.com/skuid/skuid-cli/pkg/util"
+)
+
+func TestValidateDirectory(t *testing.T) {
+	valid := map[string]string{
+		"pages/Home.json":         `{"name":"Home"}`,
+		"pages/Home.xml":          "<skuidpage theme=\"Dark\">\n\t<models>\n\t\t<model id=\"Orders\" datasource=\"Orders\"/>\n\t\t<model id=\"Filters\" datasource=\"Ui-Only\"/>\n\t</models>\n</skuidpage>",
+		"pages/Other.skuid.json":  `{"name":"Other"}`,
+		"pages/Other.xml":         `<skuidpage/>`,
+		"themes/Dark.json":        `{"name":"Dark"}`,
+		"themes/Dark.inline.css":  `body {}`,
+		"datasources/Orders.json": `{"name":"Orders"}`,
+		"apps/Sales.json":         "{\n\t\"name\": \"Sales\",\n\t\"routes\": [{\"page\": \"Home\"}]\n}",
+	}
+
+	for _, tc := range []struct {
+		description string
+		files       map[string]string
+		remove      []string
+		remote      pkg.NlxMetadata
+		expected    []string
+	}{
+		{
+			description: "valid",
+		},
+		{
+			description: "invalid json",
+			files:       map[string]string{"datasources/Orders.json": "{\n\t\"name\": \"Orders\",\n}"},
+			expected:    []string{"datasources/Orders.json:3: invalid json: invalid character '}' looking for beginning of object key string"},
+		},
+		{
+			description: "invalid xml",
+			files:       map[string]string{"pages/Other.xml": "<skuidpage>\n\t<models>\n</skuidpage>"},
+			expected:    []string{"pages/Other.xml:3: invalid xml: XML syntax error on line 3: element <models> closed by </skuidpage>"},
+		},
+		{
+			description: "missing companions",
+			remove:      []string{"pages/Other.xml", "themes/Dark.inline.css"},
+			files:       map[string]string{"pages/Draft.xml": `<skuidpage/>`},
+			expected: []string{
+				"pages/Draft.xml: page Draft has no Draft.json or Draft.skuid.json",
+				"pages/Other.skuid.json: page Other has no Other.xml",
+				"themes/Dark.json: theme Dark has no Dark.inline.css",
+			},
+		},
+		{
+			description: "references missing locally and remotely",
+			remove:      []string{"themes/Dark.json", "themes/Dark.inline.css", "datasources/Orders.json", "pages/Home.json", "pages/Home.xml"},
+			expected: []string{
+				"apps/Sales.json:3: app Sales refers to page Home, which isn't in the directory or on the site",
+			},
+		},
+		{
+			description: "built in data sources",
+			remove:      []string{"datasources/Orders.json"},
+			remote:      pkg.NlxMetadata{DataSources: []string{"Orders"}},
+		},
+		{
+			description: "references on the site",
+			remove:      []string{"themes/Dark.json", "themes/Dark.inline.css", "datasources/Orders.json"},
+			remote:      pkg.NlxMetadata{Themes: []string{"Dark"}, DataSources: []string{"Orders"}},
+		},
+		{
+			description: "references missing from the site",
+			remove:      []string{"themes/Dark.json", "themes/Dark.inline.css", "datasources/Orders.json"},
+			remote:      pkg.NlxMetadata{Themes: []string{"Dark"}},
+			expected: []string{
+				"pages/Home.xml:3: page Home refers to datasource Orders, which isn't in the directory or on the site",
+			},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			files := make(map[string]string)
+			for path, content := range valid {
+				files[path] = content
+			}
+			for path, content := range tc.files {
+				files[path] = content
+			}
+			for _, path := range tc.remove {
+				delete(files, path)
+			}
+			dir := writeTestSite(t, files)
+
+			problems, err := pkg.ValidateDirectory(dir, nil, nil, func() (pkg.NlxMetadata, error) {
+				return tc.remote, nil
+			})
+			assert.NoError(t, err)
+
+			var actual []string
+			for _, problem := range problems {
+				actual = append(actual, problem.String())
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestValidateDirectoryFiltered(t *testing.T) {
+	dir := writeTestSite(t, map[string]string{
+		"pages/Home.json":     `{"name":"Home"}`,
+		"pages/Home.xml":      `<skuidpage/>`,
+		"pages/Broken.json":   `{`,
+		"pages/Broken.xml":    `<skuidpage/>`,
+		"variables/Url.json":  `{"value": ${SKUID_ENV:PORT}}`,
+		"variables/Name.json": `{"value": "${SKUID_ENV:NAME}"}`,
+	})
+
+	// only what's deployed is checked, with variables substituted
+	filter := pkg.NlxMetadata{Pages: []string{"Home"}, Variables: []string{"Url", "Name"}}
+	problems, err := pkg.ValidateDirectory(dir, &filter, util.Variables{"PORT": "3000", "NAME": "site"}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+
+	problems, err = pkg.ValidateDirectory(dir, nil, util.Variables{"PORT": "3000", "NAME": "site"}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, problems, 1) {
+		assert.Equal(t, "pages/Broken.json", problems[0].Path)
+		assert.Equal(t, 1, problems[0].Line)
+	}
+}
+
+func TestValidateDirectoryRemoteError(t *testing.T) {
+	dir := writeTestSite(t, map[string]string{
+		"pages/Home.json": `{"name":"Home"}`,
+		"pages/Home.xml":  `<skuidpage theme="Dark"/>`,
+	})
+
+	_, err := pkg.ValidateDirectory(dir, nil, nil, func() (pkg.NlxMetadata, error) {
+		return pkg.NlxMetadata{}, errors.New("unauthorized")
+	})
+	assert.EqualError(t, err, "unauthorized")
+}
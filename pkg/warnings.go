@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/viper"
+
+	"github.com/skuid/skuid-cli/pkg/constants"
+)
+
+const (
+	WARNING_ALLOWED   = "allowed"
+	WARNING_DENIED    = "denied"
+	WARNING_UNMATCHED = "unmatched"
+
+	// DEPLOY_STAGE_WARNINGS is the stage of a deploy that evaluates the
+	// warning policy, before anything is deployed
+	DEPLOY_STAGE_WARNINGS = "warnings"
+)
+
+// DeployWarning is a warning about a deploy, and what the warning policy made
+// of it
+type DeployWarning struct {
+	// Service is the plan the warning came from, if any
+	Service string `json:"service,omitempty"`
+	Message string `json:"message"`
+	Policy  string `json:"policy,omitempty"`
+}
+
+// PlanWarnings returns the warnings of each plan, then any warnings beyond
+// the plans' own
+func PlanWarnings(plans NlxDynamicPlanMap, warnings []string) (planWarnings []DeployWarning) {
+	for _, name := range SortedPlanNames(plans) {
+		for _, warning := range plans[name].Warnings {
+			planWarnings = append(planWarnings, DeployWarning{Service: name, Message: warning})
+		}
+	}
+	for _, warning := range warnings {
+		planWarnings = append(planWarnings, DeployWarning{Message: warning})
+	}
+	return
+}
+
+// WarningPolicy decides which warnings stop a deploy before it starts. A
+// warning matching a deny pattern always does, even if it also matches an
+// allow pattern. With FailOnWarnings, so does any warning that doesn't match
+// an allow pattern.
+//
+// The patterns are read from the .skuid config file:
+//
+//	warnings:
+//	  allow:
+//	    - has no permissions$
+//	  deny:
+//	    - will not be deleted
+type WarningPolicy struct {
+	FailOnWarnings bool
+	Allow          []*regexp.Regexp
+	Deny           []*regexp.Regexp
+}
+
+// NewWarningPolicy compiles the allow and deny patterns
+func NewWarningPolicy(failOnWarnings bool, allow, deny []string) (policy WarningPolicy, err error) {
+	policy.FailOnWarnings = failOnWarnings
+	if policy.Allow, err = compileWarningPatterns(constants.CONFIG_WARNINGS_ALLOW, allow); err != nil {
+		return
+	}
+	policy.Deny, err = compileWarningPatterns(constants.CONFIG_WARNINGS_DENY, deny)
+	return
+}
+
+func compileWarningPatterns(key string, patterns []string) (compiled []*regexp.Regexp, err error) {
+	for _, pattern := range patterns {
+		var re *regexp.Regexp
+		if re, err = regexp.Compile(pattern); err != nil {
+			err = fmt.Errorf("invalid %v pattern %q: %w", key, pattern, err)
+			return
+		}
+		compiled = append(compiled, re)
+	}
+	return
+}
+
+// GetWarningPolicy reads the warning policy's patterns from the config file
+func GetWarningPolicy(failOnWarnings bool) (WarningPolicy, error) {
+	return NewWarningPolicy(
+		failOnWarnings,
+		viper.GetStringSlice(constants.CONFIG_WARNINGS_ALLOW),
+		viper.GetStringSlice(constants.CONFIG_WARNINGS_DENY),
+	)
+}
+
+// Evaluate sets the policy of each warning, and returns an error if any of
+// them stop the deploy
+func (policy WarningPolicy) Evaluate(warnings []DeployWarning) (evaluated []DeployWarning, err error) {
+	denied, unmatched := 0, 0
+	for _, warning := range warnings {
+		switch {
+		case matchesAny(policy.Deny, warning.Message):
+			warning.Policy = WARNING_DENIED
+			denied++
+		case matchesAny(policy.Allow, warning.Message):
+			warning.Policy = WARNING_ALLOWED
+		default:
+			warning.Policy = WARNING_UNMATCHED
+			unmatched++
+		}
+		evaluated = append(evaluated, warning)
+	}
+
+	if denied > 0 {
+		err = fmt.Errorf("deployment plan has %v denied warning(s)", denied)
+	} else if policy.FailOnWarnings && unmatched > 0 {
+		err = fmt.Errorf("deployment plan has %v warning(s)", unmatched)
+	}
+	if err != nil {
+		err = &DeployStageError{Stage: DEPLOY_STAGE_WARNINGS, Err: err}
+	}
+	return
+}
+
+func matchesAny(patterns []*regexp.Regexp, message string) bool {
+	for _, re := range patterns {
+		if re.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
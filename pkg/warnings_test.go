@@ -0,0 +1,94 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skuid/skuid-cli/pkg"
+)
+
+func TestPlanWarnings(t *testing.T) {
+	plans := pkg.NlxDynamicPlanMap{
+		pkg.DATA_PLAN_KEY:     pkg.NlxPlan{Warnings: []string{"datasource Orders has no permissions"}},
+		pkg.METADATA_PLAN_KEY: pkg.NlxPlan{Warnings: []string{"page Other has no app"}},
+	}
+
+	assert.Equal(t, []pkg.DeployWarning{
+		{Service: pkg.METADATA_PLAN_KEY, Message: "page Other has no app"},
+		{Service: pkg.DATA_PLAN_KEY, Message: "datasource Orders has no permissions"},
+		{Message: "pages Old was deleted locally"},
+	}, pkg.PlanWarnings(plans, []string{"pages Old was deleted locally"}))
+}
+
+func TestWarningPolicy(t *testing.T) {
+	warnings := []pkg.DeployWarning{
+		{Message: "datasource Orders has no permissions"},
+		{Message: "page Other has no app"},
+		{Message: "pages Old was deleted locally and will not be deleted from the site"},
+	}
+
+	for _, tc := range []struct {
+		description    string
+		failOnWarnings bool
+		allow          []string
+		deny           []string
+		expected       []string
+		expectedErr    string
+	}{
+		{
+			description: "no policy",
+			expected:    []string{pkg.WARNING_UNMATCHED, pkg.WARNING_UNMATCHED, pkg.WARNING_UNMATCHED},
+		},
+		{
+			description:    "fail on warnings",
+			failOnWarnings: true,
+			expected:       []string{pkg.WARNING_UNMATCHED, pkg.WARNING_UNMATCHED, pkg.WARNING_UNMATCHED},
+			expectedErr:    "deployment plan has 3 warning(s)",
+		},
+		{
+			description:    "allowed warnings don't fail",
+			failOnWarnings: true,
+			allow:          []string{"has no permissions$", "^page "},
+			expected:       []string{pkg.WARNING_ALLOWED, pkg.WARNING_ALLOWED, pkg.WARNING_UNMATCHED},
+			expectedErr:    "deployment plan has 1 warning(s)",
+		},
+		{
+			description: "denied warnings fail without fail on warnings",
+			deny:        []string{"will not be deleted"},
+			expected:    []string{pkg.WARNING_UNMATCHED, pkg.WARNING_UNMATCHED, pkg.WARNING_DENIED},
+			expectedErr: "deployment plan has 1 denied warning(s)",
+		},
+		{
+			description: "deny wins over allow",
+			allow:       []string{".*"},
+			deny:        []string{"Orders"},
+			expected:    []string{pkg.WARNING_DENIED, pkg.WARNING_ALLOWED, pkg.WARNING_ALLOWED},
+			expectedErr: "deployment plan has 1 denied warning(s)",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			policy, err := pkg.NewWarningPolicy(tc.failOnWarnings, tc.allow, tc.deny)
+			assert.NoError(t, err)
+
+			evaluated, err := policy.Evaluate(warnings)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			var policies []string
+			for i, warning := range evaluated {
+				assert.Equal(t, warnings[i].Message, warning.Message)
+				policies = append(policies, warning.Policy)
+			}
+			assert.Equal(t, tc.expected, policies)
+		})
+	}
+}
+
+func TestWarningPolicyInvalidPattern(t *testing.T) {
+	_, err := pkg.NewWarningPolicy(false, nil, []string{"("})
+	assert.ErrorContains(t, err, "invalid warnings.deny pattern")
+}
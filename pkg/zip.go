@@ -4,20 +4,32 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gookit/color"
 	"github.com/skuid/skuid-cli/pkg/logging"
+	"github.com/skuid/skuid-cli/pkg/util"
 	"golang.org/x/sync/errgroup"
 )
 
 // Archive compresses a file/directory to a writer
 func Archive(inFilePath string, filter *NlxMetadata) (result []byte, err error) {
+	return ArchiveWithVariables(inFilePath, filter, nil)
+}
+
+// ArchiveWithVariables compresses a file/directory to a writer, substituting
+// the variables for placeholders in the archived files
+func ArchiveWithVariables(inFilePath string, filter *NlxMetadata, variables util.Variables) (result []byte, err error) {
 	return ArchiveWithFilterFunc(inFilePath, func(relativePath string) bool {
 		if filter != nil {
 			keep := filter.FilterItem(relativePath)
@@ -26,22 +38,36 @@ func Archive(inFilePath string, filter *NlxMetadata) (result []byte, err error)
 			}
 		}
 		return true
-	})
+	}, variables)
 }
 
 // ArchivePartial compresses all files in a file/directory matching a relative prefix to a writer
-func ArchivePartial(inFilePath string, basePrefix string) ([]byte, error) {
+func ArchivePartial(inFilePath string, basePrefix string, variables util.Variables) ([]byte, error) {
 	return ArchiveWithFilterFunc(inFilePath, func(relativePath string) bool {
 		return strings.HasPrefix(relativePath, basePrefix)
-	})
+	}, variables)
 }
 
+var (
+	// ARCHIVE_MODIFIED_TIME is the modification time of every archived file,
+	// so that archives of the same files are identical
+	ARCHIVE_MODIFIED_TIME = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// ARCHIVE_FILE_MODE is the permissions of every archived file
+const ARCHIVE_FILE_MODE os.FileMode = 0644
+
 type archiveSuccess struct {
 	Bytes    []byte
 	FilePath string
 }
 
-func ArchiveWithFilterFunc(inFilePath string, filterKeep func(string) bool) (result []byte, err error) {
+// ArchiveWithFilterFunc compresses the files in a directory that filterKeep
+// keeps and that aren't hidden or ignored. ${SKUID_ENV:NAME} placeholders in
+// the archived copies are replaced with the variables; every placeholder
+// without a value is reported in the returned error. The files on disk are
+// never modified.
+func ArchiveWithFilterFunc(inFilePath string, filterKeep func(string) bool, variables util.Variables) (result []byte, err error) {
 	inFileStat, err := os.Stat(inFilePath)
 	if err != nil {
 		return nil, err
@@ -52,6 +78,11 @@ func ArchiveWithFilterFunc(inFilePath string, filterKeep func(string) bool) (res
 		return nil, errors.New(msg)
 	}
 
+	ignore, err := util.LoadIgnore(inFilePath)
+	if err != nil {
+		return nil, err
+	}
+
 	buffer := new(bytes.Buffer)
 	zipWriter := zip.NewWriter(buffer)
 
@@ -59,16 +90,15 @@ func ArchiveWithFilterFunc(inFilePath string, filterKeep func(string) bool) (res
 	g, ctx := errgroup.WithContext(context.Background())
 	ch := make(chan archiveSuccess)
 
-	err = filepath.Walk(inFilePath, func(filePath string, fileInfo os.FileInfo, e error) (err error) {
+	// collect every unresolved placeholder rather than stopping at the first
+	var unresolvedMutex sync.Mutex
+	var unresolved []util.UnresolvedPlaceholdersError
+
+	walkErr := filepath.Walk(inFilePath, func(filePath string, fileInfo os.FileInfo, e error) (err error) {
 		if e != nil {
 			return e
 		}
 
-		if fileInfo.IsDir() {
-			logging.Get().Debugf("Zipping: %v", color.Cyan.Sprint(filePath))
-			return
-		}
-
 		// we only want the immediate directory and the filename for the archive path
 		var archivePath string
 		if archivePath, err = filepath.Rel(inFilePath, filePath); err != nil {
@@ -76,6 +106,19 @@ func ArchiveWithFilterFunc(inFilePath string, filterKeep func(string) bool) (res
 			return
 		}
 
+		if ignore.Ignored(archivePath, fileInfo.IsDir()) {
+			logging.Get().Debugf(color.Gray.Sprintf("Ignoring %v: %v", util.SKUIDIGNORE_FILE, filePath))
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return
+		}
+
+		if fileInfo.IsDir() {
+			logging.Get().Debugf("Zipping: %v", color.Cyan.Sprint(filePath))
+			return
+		}
+
 		if strings.HasPrefix(archivePath, ".") {
 			logging.Get().Debugf(color.Gray.Sprintf("Ignoring hidden file: %v", filePath))
 			return
@@ -95,9 +138,20 @@ func ArchiveWithFilterFunc(inFilePath string, filterKeep func(string) bool) (res
 				logging.Get().Warnf("Error Processing %v: %v", filePath, err)
 				return
 			}
+			if fileBytes, err = variables.Substitute(archivePath, fileBytes); err != nil {
+				var unresolvedErr util.UnresolvedPlaceholdersError
+				if errors.As(err, &unresolvedErr) {
+					unresolvedMutex.Lock()
+					unresolved = append(unresolved, unresolvedErr)
+					unresolvedMutex.Unlock()
+					return nil
+				}
+				return
+			}
 			success := archiveSuccess{
-				Bytes:    fileBytes,
-				FilePath: archivePath,
+				Bytes: fileBytes,
+				// zip entry names always use forward slashes
+				FilePath: filepath.ToSlash(util.FromWindowsPath(archivePath)),
 			}
 			select {
 			case ch <- success:
@@ -109,24 +163,54 @@ func ArchiveWithFilterFunc(inFilePath string, filterKeep func(string) bool) (res
 		return
 	})
 
+	var waitErr error
 	go func() {
-		err = g.Wait()
+		waitErr = g.Wait()
 		close(ch) // after all workers in group are done, we can close channel to begin range
-		if err != nil {
-			logging.Get().WithError(err).Fatal("failed during ArchiveWithFilterFunc")
-		}
 	}()
 
+	// files finish archiving in any order, so they're collected and sorted
+	// for the archive to be the same every time
+	var archived []archiveSuccess
 	for success := range ch {
-		var zipFileWriter io.Writer
 		logging.Get().Tracef("Finished Processing %v", color.Green.Sprint(success.FilePath))
-		zipFileWriter, err = zipWriter.Create(success.FilePath)
-		if err != nil {
+		archived = append(archived, success)
+	}
+
+	// the channel is closed, so the workers are done. A walk that stopped
+	// part way would leave files out of the archive.
+	if walkErr != nil {
+		logging.Get().WithError(walkErr).Error("failed during ArchiveWithFilterFunc")
+		return nil, walkErr
+	}
+	if waitErr != nil {
+		logging.Get().WithError(waitErr).Error("failed during ArchiveWithFilterFunc")
+		return nil, waitErr
+	}
+	if len(unresolved) > 0 {
+		sort.Slice(unresolved, func(i, j int) bool { return unresolved[i].Path < unresolved[j].Path })
+		errs := make([]error, len(unresolved))
+		for i, e := range unresolved {
+			errs[i] = e
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	sort.Slice(archived, func(i, j int) bool { return archived[i].FilePath < archived[j].FilePath })
+	for _, success := range archived {
+		header := &zip.FileHeader{
+			Name:     success.FilePath,
+			Method:   zip.Deflate,
+			Modified: ARCHIVE_MODIFIED_TIME,
+		}
+		header.SetMode(ARCHIVE_FILE_MODE)
+
+		var zipFileWriter io.Writer
+		if zipFileWriter, err = zipWriter.CreateHeader(header); err != nil {
 			logging.Get().Errorf("Error processing %v: %v", success.FilePath, err)
 			return
 		}
-		_, err = zipFileWriter.Write(success.Bytes)
-		if err != nil {
+		if _, err = zipFileWriter.Write(success.Bytes); err != nil {
 			logging.Get().Errorf("Error writing %v: %v", success.FilePath, err)
 			return
 		}
@@ -137,3 +221,27 @@ func ArchiveWithFilterFunc(inFilePath string, filterKeep func(string) bool) (res
 
 	return
 }
+
+// ArchiveChecksum returns the sha256 of the names and contents of the files
+// in an archive, in the format of sha256sum's output. It doesn't depend on
+// how the files were compressed, so it's the same for the same files on
+// any machine.
+func ArchiveChecksum(payload []byte) (checksum string, err error) {
+	var hashes map[string]string
+	if hashes, err = ArchiveHashes(payload); err != nil {
+		return
+	}
+
+	names := make([]string, 0, len(hashes))
+	for name := range hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%v  %v\n", hashes[name], name)
+	}
+	checksum = hex.EncodeToString(hash.Sum(nil))
+	return
+}
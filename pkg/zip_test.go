@@ -1,9 +1,15 @@
 package pkg_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 
 	"github.com/skuid/skuid-cli/pkg"
 	"github.com/skuid/skuid-cli/pkg/logging"
@@ -21,3 +27,138 @@ func TestZip(t *testing.T) {
 	logging.Get().Info(len(bb))
 
 }
+
+func TestArchiveSkuidIgnore(t *testing.T) {
+	dir := writeTestSite(t, map[string]string{
+		".skuidignore":          "*.md\npages/fixtures/\n",
+		"README.md":             "docs",
+		"pages/Home.json":       `{"name":"Home"}`,
+		"pages/NOTES.md":        "notes",
+		"pages/fixtures/a.json": `{"name":"a"}`,
+	})
+
+	for _, archive := range []func() ([]byte, error){
+		func() ([]byte, error) { return pkg.Archive(dir, nil) },
+		func() ([]byte, error) { return pkg.ArchivePartial(dir, "pages", nil) },
+	} {
+		data, err := archive()
+		assert.NoError(t, err)
+
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		assert.NoError(t, err)
+		var names []string
+		for _, file := range reader.File {
+			names = append(names, file.Name)
+		}
+		assert.Equal(t, []string{"pages/Home.json"}, names)
+	}
+}
+
+func TestArchiveWithVariables(t *testing.T) {
+	dir := writeTestSite(t, map[string]string{
+		"datasources/Orders.json": `{"name":"Orders","url":"${SKUID_ENV:URL}"}`,
+		"pages/Home.json":         `{"name":"Home","title":"${SKUID_ENV:TITLE}","other":"${SKUID_ENV:OTHER}"}`,
+		"pages/Plain.json":        `{"name":"Plain"}`,
+	})
+
+	_, err := pkg.Archive(dir, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "datasources/Orders.json has unresolved placeholders: URL")
+		assert.Contains(t, err.Error(), "pages/Home.json has unresolved placeholders: OTHER, TITLE")
+	}
+
+	data, err := pkg.ArchiveWithVariables(dir, nil, util.Variables{"URL": "https://prod", "TITLE": "Home", "OTHER": "x"})
+	assert.NoError(t, err)
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	for _, file := range reader.File {
+		if file.Name != "datasources/Orders.json" {
+			continue
+		}
+		r, err := file.Open()
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(r)
+		assert.Equal(t, `{"name":"Orders","url":"https://prod"}`, string(body))
+	}
+
+	// the local files are untouched
+	body, err := os.ReadFile(filepath.Join(dir, "datasources", "Orders.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "${SKUID_ENV:URL}")
+}
+
+func TestArchiveIsDeterministic(t *testing.T) {
+	files := map[string]string{
+		"pages/Home.json":         `{"name":"Home"}`,
+		"pages/Home.xml":          `<skuidpage/>`,
+		"apps/Sales.json":         `{"name":"Sales"}`,
+		"datasources/Orders.json": `{"name":"Orders"}`,
+	}
+	dir := writeTestSite(t, files)
+
+	first, err := pkg.Archive(dir, nil)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		again, err := pkg.Archive(dir, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, first, again)
+	}
+
+	// the same files elsewhere, with other times and permissions, archive the same
+	other := writeTestSite(t, files)
+	later := time.Now().Add(time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(other, "pages", "Home.json"), later, later))
+	assert.NoError(t, os.Chmod(filepath.Join(other, "apps", "Sales.json"), 0600))
+	elsewhere, err := pkg.Archive(other, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, first, elsewhere)
+
+	reader, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	assert.NoError(t, err)
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+		assert.Equal(t, pkg.ARCHIVE_FILE_MODE, file.Mode())
+		assert.True(t, pkg.ARCHIVE_MODIFIED_TIME.Equal(file.Modified), file.Modified)
+	}
+	assert.Equal(t, []string{"apps/Sales.json", "datasources/Orders.json", "pages/Home.json", "pages/Home.xml"}, names)
+}
+
+func TestArchiveChecksum(t *testing.T) {
+	dir := writeTestSite(t, map[string]string{
+		"pages/Home.json": `{"name":"Home","title":"${SKUID_ENV:TITLE}"}`,
+	})
+
+	checksum := func(variables util.Variables) string {
+		payload, err := pkg.ArchiveWithVariables(dir, nil, variables)
+		assert.NoError(t, err)
+		sum, err := pkg.ArchiveChecksum(payload)
+		assert.NoError(t, err)
+		return sum
+	}
+
+	sum := checksum(util.Variables{"TITLE": "Home"})
+	assert.Len(t, sum, 64)
+	assert.Equal(t, sum, checksum(util.Variables{"TITLE": "Home"}))
+	// deployed content differs with other variables
+	assert.NotEqual(t, sum, checksum(util.Variables{"TITLE": "Welcome"}))
+}
+
+func TestArchiveUnreadableDirectory(t *testing.T) {
+	dir := writeTestSite(t, map[string]string{
+		"pages/Home.json":         `{"name":"Home"}`,
+		"pages/Home.xml":          `<skuidpage/>`,
+		"datasources/Orders.json": `{"name":"Orders"}`,
+	})
+	pages := filepath.Join(dir, "pages")
+	assert.NoError(t, os.Chmod(pages, 0))
+	defer func() { _ = os.Chmod(pages, 0755) }()
+	if _, err := os.ReadDir(pages); err == nil {
+		t.Skip("directory permissions aren't enforced for this user")
+	}
+
+	// a partial archive would be hashed and deployed as if it were everything
+	_, err := pkg.Archive(dir, nil)
+	assert.Error(t, err)
+}